@@ -0,0 +1,23 @@
+package db
+
+import (
+	"log"
+	"os"
+
+	"Bakery_Pos/module"
+)
+
+var Attempts module.AttemptStore
+
+// Connect_Attempts selects where failed logins are counted from
+// ATTEMPT_STORE: memory (default, single instance) or postgres.
+func Connect_Attempts() {
+	switch os.Getenv("ATTEMPT_STORE") {
+	case "", "memory":
+		Attempts = module.NewMemoryAttemptStore()
+	case "postgres":
+		Attempts = module.PostgresAttemptStore{DB: DB}
+	default:
+		log.Fatalf("Unknown ATTEMPT_STORE %q", os.Getenv("ATTEMPT_STORE"))
+	}
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"Bakery_Pos/models"
+	"Bakery_Pos/module"
 	"log"
 	"os"
 
@@ -29,6 +30,22 @@ func Connect_DB() {
 
 	log.Println("✅ Connected to Supabase PostgreSQL")
 
+	// AutoMigrate does not update an existing check constraint, so drop the
+	// order status one and let it be recreated with the current statuses
+	if err := DB.Exec("ALTER TABLE IF EXISTS orders DROP CONSTRAINT IF EXISTS chk_orders_status").Error; err != nil {
+		log.Fatalf("Failed to drop orders status constraint: %v", err)
+	}
+	// counter sales have no customer and may list a product twice with
+	// different modifiers
+	for _, stmt := range []string{
+		"ALTER TABLE IF EXISTS orders ALTER COLUMN user_id DROP NOT NULL",
+		"DROP INDEX IF EXISTS idx_order_product",
+	} {
+		if err := DB.Exec(stmt).Error; err != nil {
+			log.Fatalf("Failed to prepare orders for counter sales: %v", err)
+		}
+	}
+
 	if err := DB.AutoMigrate(
 		&models.User{},
 		&models.Cart{},
@@ -38,11 +55,32 @@ func Connect_DB() {
 		&models.Promotion{},
 		&models.Order{},
 		&models.OrderItem{},
+		&models.AuditLog{},
+		&models.Session{},
+		&models.PasswordResetToken{},
+		&models.LoginAttempt{},
+		&models.MFABackupCode{},
+		&models.APIKey{},
+		&models.Address{},
+		&models.DataRequest{},
+		&models.LoyaltyEntry{},
+		&models.LoyaltyRule{},
+		&models.MembershipTier{},
+		&models.OrderPayment{},
+		&models.GiftCard{},
+		&models.GiftCardTransaction{},
+		&models.StoreCreditEntry{},
+		&models.Shift{},
+		&models.CashMovement{},
 	); err != nil {
 		log.Fatalf("AutoMigrate failed: %v", err)
 	}
 	log.Println("✅ Auto Migration completed")
 
+	if err := module.SeedTiers(DB); err != nil {
+		log.Printf("Warning: failed to create default membership tiers: %v", err)
+	}
+
 	// Ensure sequences are in sync with table max(id) to avoid duplicate key errors
 	// This can happen if rows were inserted manually or restored without updating the sequence.
 	// Adjust the sequence for promotions table.
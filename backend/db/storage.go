@@ -1,29 +1,122 @@
 package db
 
 import (
+	"context"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
+	"Bakery_Pos/module"
+	"Bakery_Pos/storage"
 	storageapi "Bakery_Pos/superbase-storage-api"
 )
 
-var Storage *storageapi.Client
+var Storage storage.ObjectStore
 
+// Connect_Storage selects the object store from STORAGE_DRIVER (supabase by
+// default, s3 or local) and makes sure the buckets exist.
 func Connect_Storage() {
+	driver := os.Getenv("STORAGE_DRIVER")
+	if driver == "" {
+		driver = "supabase"
+	}
+
+	var err error
+	switch driver {
+	case "supabase":
+		Storage, err = connectSupabase()
+	case "s3":
+		Storage, err = connectS3()
+	case "local":
+		Storage, err = connectLocal()
+	default:
+		log.Fatalf("Unknown STORAGE_DRIVER %q", driver)
+	}
+	if err != nil {
+		log.Fatalf("❌ Failed to set up %s storage: %v", driver, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := Storage.CreateBucket(ctx, module.ProductImageBucket, true); err != nil {
+		log.Printf("⚠️ Failed to set up bucket %s: %v", module.ProductImageBucket, err)
+	}
+	// slips show customers' bank details, so they are only served through signed URLs
+	if err := Storage.CreateBucket(ctx, module.SlipBucket, false); err != nil {
+		log.Printf("⚠️ Failed to set up bucket %s: %v", module.SlipBucket, err)
+	}
+}
+
+func connectSupabase() (storage.ObjectStore, error) {
 	projectID := os.Getenv("PROJECT_ID")
 	if projectID == "" {
-		log.Fatal("STORAGE_ACCESS is not set")
+		log.Fatal("PROJECT_ID is not set")
 	}
 	anonKey := os.Getenv("ANON_KEY")
 	if anonKey == "" {
-		log.Fatal("STORAGE_SECRET is not set")
+		log.Fatal("ANON_KEY is not set")
 	}
 	secretKey := os.Getenv("SECRET_KEY")
 	if secretKey == "" {
-		log.Fatal("STORAGE_SECRET is not set")
+		log.Fatal("SECRET_KEY is not set")
+	}
+
+	// STORAGE_TIMEOUT (e.g. 30s) bounds each request, STORAGE_RETRIES the
+	// extra attempts for idempotent requests
+	var opts []storageapi.Option
+	if timeout, err := time.ParseDuration(os.Getenv("STORAGE_TIMEOUT")); err == nil && timeout > 0 {
+		opts = append(opts, storageapi.WithTimeout(timeout))
+	}
+	if retries, err := strconv.Atoi(os.Getenv("STORAGE_RETRIES")); err == nil && retries >= 0 {
+		opts = append(opts, storageapi.WithRetry(retries, 200*time.Millisecond))
 	}
 
-	Storage = storageapi.NewClient(projectID, anonKey, secretKey)
-	Storage.CreateBucket("product-images", true)
-	Storage.CreateBucket("order-slips", true)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return storageapi.NewClient(ctx, projectID, anonKey, secretKey, opts...)
+}
+
+func connectS3() (storage.ObjectStore, error) {
+	endpoint := os.Getenv("S3_ENDPOINT")
+	if endpoint == "" {
+		log.Fatal("S3_ENDPOINT is not set")
+	}
+	useSSL, _ := strconv.ParseBool(os.Getenv("S3_USE_SSL"))
+
+	store, err := storage.NewS3Store(storage.S3Config{
+		Endpoint:  endpoint,
+		AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("S3_SECRET_KEY"),
+		Region:    os.Getenv("S3_REGION"),
+		UseSSL:    useSSL,
+		PublicURL: os.Getenv("S3_PUBLIC_URL"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("✅ Connected to S3 storage at %s", endpoint)
+	return store, nil
+}
+
+func connectLocal() (storage.ObjectStore, error) {
+	root := os.Getenv("STORAGE_LOCAL_DIR")
+	if root == "" {
+		root = "./uploads"
+	}
+	baseURL := os.Getenv("STORAGE_PUBLIC_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:5000" + storage.LocalMountPath
+	}
+	key := os.Getenv("STORAGE_SIGNING_KEY")
+	if key == "" {
+		key = os.Getenv("JWT_SECRET")
+	}
+
+	store, err := storage.NewLocalStore(root, baseURL, []byte(key))
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("✅ Using local storage in %s", root)
+	return store, nil
 }
@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Every runs fn every interval in the background until ctx is cancelled.
+// Each run gets its own timeout of one interval so a stuck run cannot pile up.
+func Every(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				runCtx, cancel := context.WithTimeout(ctx, interval)
+				start := time.Now()
+				if err := fn(runCtx); err != nil {
+					log.Printf("❌ Job %s failed: %v", name, err)
+				} else {
+					log.Printf("✅ Job %s finished in %s", name, time.Since(start).Round(time.Millisecond))
+				}
+				cancel()
+			}
+		}
+	}()
+}
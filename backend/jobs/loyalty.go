@@ -0,0 +1,25 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/module"
+
+	"gorm.io/gorm"
+)
+
+// StartLoyaltyExpiry hourly writes off loyalty points past their expiry.
+func StartLoyaltyExpiry(ctx context.Context) {
+	Every(ctx, "loyalty-expiry", time.Hour, func(ctx context.Context) error {
+		return db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			expired, err := module.ExpirePoints(tx, time.Now())
+			if expired > 0 {
+				log.Printf("Expired %d loyalty points", expired)
+			}
+			return err
+		})
+	})
+}
@@ -0,0 +1,20 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/models"
+)
+
+// StartSessionCleanup hourly deletes sessions that expired or were revoked
+// more than a day ago.
+func StartSessionCleanup(ctx context.Context) {
+	Every(ctx, "session-cleanup", time.Hour, func(ctx context.Context) error {
+		cutoff := time.Now().Add(-24 * time.Hour)
+		return db.DB.WithContext(ctx).
+			Where("expires_at < ? OR revoked_at < ?", cutoff, cutoff).
+			Delete(&models.Session{}).Error
+	})
+}
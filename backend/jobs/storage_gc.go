@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/module"
+)
+
+// StartStorageGC schedules the orphaned object cleanup when
+// STORAGE_GC_INTERVAL is set (e.g. 24h). STORAGE_GC_GRACE overrides the grace
+// period, STORAGE_GC_DRY_RUN=true only logs what would be deleted.
+func StartStorageGC(ctx context.Context) {
+	interval, err := time.ParseDuration(os.Getenv("STORAGE_GC_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return
+	}
+	grace := module.DefaultStorageGCGrace
+	if d, err := time.ParseDuration(os.Getenv("STORAGE_GC_GRACE")); err == nil && d >= 0 {
+		grace = d
+	}
+	dryRun := os.Getenv("STORAGE_GC_DRY_RUN") == "true"
+
+	Every(ctx, "storage-gc", interval, func(ctx context.Context) error {
+		resp, err := module.CollectStorageGarbage(ctx, db.DB, db.Storage, grace, dryRun)
+		if err != nil {
+			return err
+		}
+		log.Printf("Storage GC: scanned %d objects, %d orphans, %d deleted, %d dangling images, %d errors",
+			resp.Scanned, len(resp.Orphans), resp.Deleted, len(resp.DanglingImages), len(resp.Errors))
+		if resp.Deleted == 0 {
+			return nil
+		}
+		return module.RecordAudit(db.DB, module.NewAuditLog("", "storage.gc", "storage", "", nil, resp))
+	})
+	log.Printf("✅ Storage GC scheduled every %s", interval)
+}
@@ -0,0 +1,22 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/module"
+)
+
+// StartTierRecalculation daily moves users to the tier matching their spend
+// over the last 12 months, so old orders age out.
+func StartTierRecalculation(ctx context.Context) {
+	Every(ctx, "tier-recalculation", 24*time.Hour, func(ctx context.Context) error {
+		changed, err := module.RecalculateTiers(db.DB.WithContext(ctx), nil, time.Now())
+		if changed > 0 {
+			log.Printf("Moved %d users to another tier", changed)
+		}
+		return err
+	})
+}
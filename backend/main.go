@@ -1,16 +1,24 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
 	"strings"
+	"time"
 
 	"Bakery_Pos/db"
+	"Bakery_Pos/jobs"
 	"Bakery_Pos/middleware"
+	"Bakery_Pos/models"
+	"Bakery_Pos/notify"
 	"Bakery_Pos/routes"
 	"Bakery_Pos/routes_admin"
+	"Bakery_Pos/storage"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/joho/godotenv"
 
 	_ "Bakery_Pos/docs"
@@ -24,6 +32,14 @@ import (
 // @host localhost:5000
 // @BasePath /api
 // @schemes http
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description "Bearer <access token>". Takes precedence over the Authorization cookie.
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description API key from /admin/api-keys. Takes precedence over any token.
 func main() {
 	err := godotenv.Load()
 	if err != nil {
@@ -32,62 +48,110 @@ func main() {
 
 	db.Connect_DB()
 	db.Connect_Storage()
-
+	db.Connect_Attempts()
+	notify.Setup()
+	jobs.StartStorageGC(context.Background())
+	jobs.StartSessionCleanup(context.Background())
+	jobs.StartLoyaltyExpiry(context.Background())
+	jobs.StartTierRecalculation(context.Background())
+
+	// c.IP() only reads X-Forwarded-For when the request comes from one of
+	// TRUSTED_PROXIES, so limiters, login guards, audit and sessions can't be
+	// fed a made-up address. Fiber takes the first address in the header, so
+	// the proxy must overwrite it (nginx: $remote_addr) rather than append.
 	app := fiber.New(fiber.Config{
-		StrictRouting: false,
+		StrictRouting:           false,
+		BodyLimit:               8 * 1024 * 1024,
+		ProxyHeader:             fiber.HeaderXForwardedFor,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          trustedProxies(),
+		EnableIPValidation:      true,
 	})
 	app.Use(func(c *fiber.Ctx) error {
-		forwarded := c.Get("X-Forwarded-For")
-		realIP := c.Get("X-Real-IP")
-
-		ip := c.IP()
-		if forwarded != "" {
-			parts := strings.Split(forwarded, ",")
-			ip = strings.TrimSpace(parts[0])
-		} else if realIP != "" {
-			ip = realIP
-		}
-		log.Printf("Request: %s %s, IP: %s", c.Method(), c.OriginalURL(), ip)
+		log.Printf("Request: %s %s, IP: %s", c.Method(), c.OriginalURL(), c.IP())
 		return c.Next()
 	})
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     "http://localhost:3000, http://127.0.0.1:3000, https://sweet-heven.vercel.app",
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-Auth-Transport, X-API-Key",
 		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
 		AllowCredentials: true,
 	}))
 
+	if local, ok := db.Storage.(*storage.LocalStore); ok {
+		local.Register(app.Group(storage.LocalMountPath))
+	}
+
 	api := app.Group("/api")
 
+	productsWrite := middleware.RequirePermission(models.PermProductsWrite)
+
 	api.Get("/ping", func(c *fiber.Ctx) error {
 		return c.SendString("pong")
 	})
 
+	// per IP limits on the unauthenticated endpoints that touch passwords
+	authLimit := limiter.New(limiter.Config{
+		Max:        10,
+		Expiration: time.Minute,
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Too many requests, slow down"})
+		},
+	})
+
 	user := api.Group("/user")
-	user.Post("/login", routes.LoginHandler)
+	user.Post("/login", authLimit, routes.LoginHandler)
+	user.Post("/login/mfa", authLimit, routes.LoginMFAHandler)
 	user.Post("/logout", routes.LogoutHandler)
-	user.Post("/register", routes.RegisterHandler)
-	user.Get("/setting", routes.UpdateSetting)
+	user.Post("/refresh", routes.RefreshHandler)
+	user.Post("/password/change", middleware.Auth, routes.ChangePassword)
+	user.Post("/password/reset", authLimit, routes.RequestPasswordReset)
+	user.Post("/password/reset/confirm", authLimit, routes.ConfirmPasswordReset)
+	user.Get("/sessions", middleware.Auth, routes.GetSessions)
+	user.Post("/sessions/revoke-all", middleware.Auth, routes.RevokeAllSessions)
+	user.Delete("/sessions/:session_id", middleware.Auth, routes.RevokeSession)
+	user.Post("/mfa/enroll", middleware.Auth, routes.EnrollMFA)
+	user.Post("/mfa/verify", middleware.Auth, routes.VerifyMFA)
+	user.Post("/mfa/disable", middleware.Auth, routes.DisableMFA)
+	user.Post("/mfa/backup-codes", middleware.Auth, routes.RegenerateBackupCodes)
+	user.Post("/register", authLimit, routes.RegisterHandler)
+	user.Get("/me", middleware.Auth, routes.GetProfile)
+	user.Put("/me", middleware.Auth, routes.UpdateSetting)
+	user.Delete("/me", middleware.Auth, routes.DeleteMyAccount)
+	user.Get("/me/export", middleware.Auth, routes.ExportMyData)
+	user.Put("/settings", middleware.Auth, routes.UpdateSetting)
+	user.Get("/loyalty", middleware.Auth, routes.GetLoyalty)
+	user.Get("/store-credit", middleware.Auth, routes.GetStoreCredit)
+	user.Get("/gift-cards/:code", middleware.Auth, routes.GetGiftCardBalance)
+	user.Get("/addresses", middleware.Auth, routes.GetAddresses)
+	user.Post("/addresses", middleware.Auth, routes.CreateAddress)
+	user.Put("/addresses/:address_id", middleware.Auth, routes.UpdateAddress)
+	user.Delete("/addresses/:address_id", middleware.Auth, routes.DeleteAddress)
 
 	product := api.Group("/products")
 	product.Get("/", middleware.AuthOptional, routes.GetProducts)
-	product.Post("/", middleware.Auth, middleware.Admin, routes_admin.CreateProduct)
+	product.Post("/", middleware.Auth, productsWrite, routes_admin.CreateProduct)
+	product.Get("/lookup", middleware.AuthOptional, routes.LookupProduct)
 
 	product_select := product.Group("/:id")
 	product_select.Get("/", middleware.AuthOptional, routes.GetProductByID)
-	product_select.Put("/", middleware.Auth, middleware.Admin, routes_admin.UpdateProduct)
-	product_select.Delete("/", middleware.Auth, middleware.Admin, routes_admin.DeleteProduct)
+	product_select.Put("/", middleware.Auth, productsWrite, routes_admin.UpdateProduct)
+	product_select.Delete("/", middleware.Auth, productsWrite, routes_admin.DeleteProduct)
 	product_select.Get("/images", middleware.AuthOptional, routes.GetImagesProduct)
-	product_select.Post("/images", middleware.Auth, middleware.Admin, routes_admin.UploadImagesProduct)
-	product_select.Delete("/images", middleware.Auth, middleware.Admin, routes_admin.DeleteImagesByID)
+	product_select.Post("/images", middleware.Auth, productsWrite, routes_admin.UploadImagesProduct)
+	product_select.Delete("/images", middleware.Auth, productsWrite, routes_admin.DeleteImagesByID)
+	product_select.Post("/images/append", middleware.Auth, productsWrite, routes_admin.AppendImagesProduct)
+	product_select.Put("/images/order", middleware.Auth, productsWrite, routes_admin.ReorderImagesProduct)
+	product_select.Put("/images/:image_id", middleware.Auth, productsWrite, routes_admin.UpdateImageProduct)
+	product_select.Post("/images/:image_id/confirm", middleware.Auth, productsWrite, routes_admin.ConfirmImageProduct)
 
 	// Promotions (admin)
 	promotions := api.Group("/promotions")
 	promotions.Get("/", routes_admin.GetPromotions)
-	promotions.Post("/", middleware.Auth, middleware.Admin, routes_admin.CreatePromotion)
+	promotions.Post("/", middleware.Auth, middleware.RequirePermission(models.PermPromotionsWrite), routes_admin.CreatePromotion)
 	promotions.Get(":id", routes_admin.GetPromotionByID)
-	promotions.Put(":id", middleware.Auth, middleware.Admin, routes_admin.UpdatePromotion)
-	promotions.Delete(":id", middleware.Auth, middleware.Admin, routes_admin.DeletePromotion)
+	promotions.Put(":id", middleware.Auth, middleware.RequirePermission(models.PermPromotionsWrite), routes_admin.UpdatePromotion)
+	promotions.Delete(":id", middleware.Auth, middleware.RequirePermission(models.PermPromotionsWrite), routes_admin.DeletePromotion)
 
 	cart := api.Group("/cart", middleware.Auth)
 	cart.Get("/", routes.GetCart)
@@ -98,18 +162,93 @@ func main() {
 	order := api.Group("/order", middleware.Auth)
 	order.Get("/", routes.GetAllOrders)
 	order.Get("/:order_id", routes.GetOrderByID)
-	order.Put("/:order_id", routes.UpdateOrderStatus)
+	order.Put("/:order_id", middleware.RequirePermission(models.PermOrdersUpdateStatus), routes.UpdateOrderStatus)
 	order.Delete("/:order_id", routes.DeleteOrder)
 	order.Post("/:order_id/upload-slip", routes.GenerateOrderSlipURL)
 
-	reports := api.Group("/reports", middleware.Auth, middleware.Admin)
+	pos := api.Group("/pos", middleware.Auth, middleware.RequirePermission(models.PermPOSSell))
+	pos.Get("/customers", routes.GetPOSCustomer)
+	pos.Post("/sales", routes.CreatePOSSale)
+	pos.Post("/sales/:order_id/void", routes.VoidPOSSale)
+	pos.Post("/shifts", routes.OpenShift)
+	pos.Get("/shifts/current", routes.GetCurrentShift)
+	pos.Post("/shifts/current/cash", routes.AddCashMovement)
+	pos.Post("/shifts/current/close", routes.CloseShift)
+
+	admin := api.Group("/admin", middleware.Auth)
+	admin.Post("/products/import", productsWrite, routes_admin.ImportProducts)
+	admin.Get("/products/export", productsWrite, routes_admin.ExportProducts)
+	admin.Post("/products/bulk", middleware.RequireAnyPermission(models.PermProductsWrite, models.PermInventoryWrite), routes_admin.BulkUpdateProducts)
+	admin.Get("/audit-logs", middleware.RequirePermission(models.PermAuditRead), routes_admin.GetAuditLogs)
+	admin.Get("/orders/:order_id/slip", middleware.RequirePermission(models.PermOrdersRead), routes_admin.GetOrderSlip)
+	admin.Post("/storage/gc", middleware.RequirePermission(models.PermStorageManage), routes_admin.RunStorageGC)
+	admin.Get("/roles", middleware.RequirePermission(models.PermUsersManage), routes_admin.GetRoles)
+
+	usersRead := middleware.RequirePermission(models.PermUsersRead)
+	usersManage := middleware.RequirePermission(models.PermUsersManage)
+	admin.Get("/users", usersRead, routes_admin.GetUsers)
+	admin.Get("/users/:user_id", usersRead, routes_admin.GetUserByID)
+	admin.Put("/users/:user_id/role", usersManage, routes_admin.UpdateUserRole)
+	admin.Put("/users/:user_id/status", usersManage, routes_admin.UpdateUserStatus)
+	admin.Post("/users/:user_id/reset-password", usersManage, routes_admin.ResetUserPassword)
+	admin.Delete("/users/:user_id", usersManage, routes_admin.DeleteUser)
+	admin.Delete("/users/:user_id/lockout", usersManage, routes_admin.UnlockUser)
+	admin.Delete("/users/:user_id/mfa", usersManage, routes_admin.ResetUserMFA)
+	admin.Get("/api-keys", usersManage, routes_admin.GetAPIKeys)
+	admin.Post("/api-keys", usersManage, routes_admin.CreateAPIKey)
+	admin.Delete("/api-keys/:key_id", usersManage, routes_admin.RevokeAPIKey)
+	loyaltyManage := middleware.RequirePermission(models.PermLoyaltyManage)
+	admin.Get("/loyalty/rules", loyaltyManage, routes_admin.GetLoyaltyRules)
+	admin.Post("/loyalty/rules", loyaltyManage, routes_admin.CreateLoyaltyRule)
+	admin.Put("/loyalty/rules/:rule_id", loyaltyManage, routes_admin.UpdateLoyaltyRule)
+	admin.Delete("/loyalty/rules/:rule_id", loyaltyManage, routes_admin.DeleteLoyaltyRule)
+	admin.Get("/users/:user_id/loyalty", loyaltyManage, routes_admin.GetUserLoyalty)
+	admin.Post("/users/:user_id/loyalty/adjust", loyaltyManage, routes_admin.AdjustUserLoyalty)
+	admin.Get("/tiers", loyaltyManage, routes_admin.GetTiers)
+	admin.Post("/tiers", loyaltyManage, routes_admin.CreateTier)
+	admin.Post("/tiers/recalculate", loyaltyManage, routes_admin.RecalculateTiers)
+	admin.Put("/tiers/:tier_id", loyaltyManage, routes_admin.UpdateTier)
+	admin.Delete("/tiers/:tier_id", loyaltyManage, routes_admin.DeleteTier)
+	giftCardsIssue := middleware.RequirePermission(models.PermGiftCardsIssue)
+	storedValueManage := middleware.RequirePermission(models.PermStoredValueManage)
+	admin.Get("/gift-cards", giftCardsIssue, routes_admin.GetGiftCards)
+	admin.Post("/gift-cards", giftCardsIssue, routes_admin.IssueGiftCard)
+	admin.Get("/gift-cards/:card_id", giftCardsIssue, routes_admin.GetGiftCard)
+	admin.Post("/gift-cards/:card_id/activate", giftCardsIssue, routes_admin.ActivateGiftCard)
+	admin.Post("/gift-cards/:card_id/void", storedValueManage, routes_admin.VoidGiftCard)
+	admin.Get("/users/:user_id/store-credit", storedValueManage, routes_admin.GetUserStoreCredit)
+	admin.Post("/users/:user_id/store-credit", storedValueManage, routes_admin.IssueStoreCredit)
+	admin.Post("/users/:user_id/store-credit/void", storedValueManage, routes_admin.VoidStoreCredit)
+	admin.Get("/data-requests", usersManage, routes_admin.GetDataRequests)
+	admin.Post("/data-requests", usersManage, routes_admin.CreateDataRequest)
+	admin.Get("/data-requests/:request_id/export", usersManage, routes_admin.ExportDataRequest)
+	admin.Post("/data-requests/:request_id/complete", usersManage, routes_admin.CompleteDataRequest)
+	admin.Post("/data-requests/:request_id/reject", usersManage, routes_admin.RejectDataRequest)
+
+	reports := api.Group("/reports", middleware.Auth, middleware.RequirePermission(models.PermReportsRead))
 	reports.Get("/products/top", routes_admin.GetTopProducts)
 	reports.Get("/sales/hourly", routes_admin.GetSalesByHour)
 	reports.Get("/sales/daily", routes_admin.GetSalesByDay)
 	// Product level reports
 	reports.Get("/products/sales", routes_admin.GetProductSalesSummary)
 	reports.Get("/products/:id/customers", routes_admin.GetProductCustomers)
+	reports.Get("/shifts", routes_admin.GetShifts)
+	reports.Get("/shifts/:shift_id", routes_admin.GetShiftReport)
+	reports.Post("/shifts/:shift_id/close", middleware.RequirePermission(models.PermPOSVoid), routes_admin.CloseShift)
+	reports.Get("/z", routes_admin.GetZReport)
 
 	app.Get("/*", swagger.HandlerDefault)
 	app.Listen(":5000")
 }
+
+// trustedProxies reads TRUSTED_PROXIES, a comma separated list of IPs or
+// CIDRs of the reverse proxies in front of the API. Empty means none.
+func trustedProxies() []string {
+	var proxies []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
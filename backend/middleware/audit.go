@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"Bakery_Pos/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// AuditRequest records where the audited change came from: the client IP and,
+// for requests made with an API key, which key, since those have no actor.
+func AuditRequest(c *fiber.Ctx, entry *models.AuditLog) {
+	entry.IP = c.IP()
+	if raw, _ := c.Locals("apikeyid").(string); raw != "" {
+		if id, err := uuid.Parse(raw); err == nil {
+			entry.APIKeyID = &id
+		}
+	}
+}
@@ -1,72 +1,153 @@
 package middleware
 
 import (
-	"os"
+	"errors"
+	"strconv"
 	"strings"
+	"time"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/module"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+// bearerToken returns the access token of the request, or "" when there is
+// none or it is malformed. An Authorization header takes precedence over the
+// Authorization cookie, so API clients are never affected by a stale browser
+// cookie; a malformed header does not fall back to the cookie.
+func bearerToken(c *fiber.Ctx) string {
+	if header := c.Get(fiber.HeaderAuthorization); header != "" {
+		scheme, token, _ := strings.Cut(header, " ")
+		if !strings.EqualFold(scheme, "Bearer") {
+			return ""
+		}
+		return strings.TrimSpace(token)
+	}
+	if cookie := c.Cookies("Authorization"); strings.HasPrefix(cookie, "Bearer ") {
+		return strings.TrimPrefix(cookie, "Bearer ")
+	}
+	return ""
+}
+
+// APIKeyHeader carries an API key. Requests with it are authenticated by the
+// key alone, any token is ignored.
+const APIKeyHeader = "X-API-Key"
+
+// Auth accepts a user's access token or an API key.
 func Auth(c *fiber.Ctx) error {
-	authCookie := c.Cookies("Authorization")
-	if authCookie == "" || !strings.HasPrefix(authCookie, "Bearer ") {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Missing or invalid token",
-		})
+	if key := c.Get(APIKeyHeader); key != "" {
+		return apiKeyAuth(c, key)
 	}
-	tokenString := strings.TrimPrefix(authCookie, "Bearer ")
 
-	secret := os.Getenv("JWT_SECRET")
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
-	if err != nil || !token.Valid {
+	tokenString := bearerToken(c)
+	if tokenString == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid token",
+			"error": "Missing or invalid token",
 		})
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
+	if err := authenticate(c, tokenString); err != nil {
+		if errors.Is(err, module.ErrAccountBlocked) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Account is disabled",
+			})
+		}
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid token claims",
+			"error": "Invalid token",
 		})
 	}
-
-	c.Locals("role", claims["role"])
-	c.Locals("userid", claims["userid"])
 	return c.Next()
 }
 
+// AuthOptional authenticates when a token is present. An expired or revoked
+// token is treated as a guest so public pages keep working until the client
+// refreshes.
 func AuthOptional(c *fiber.Ctx) error {
-	authCookie := c.Cookies("Authorization")
-	if authCookie == "" || !strings.HasPrefix(authCookie, "Bearer ") {
+	tokenString := bearerToken(c)
+	if tokenString == "" {
 		c.Locals("role", "guest")
 		return c.Next()
 	}
 
-	tokenString := strings.TrimPrefix(authCookie, "Bearer ")
-	secret := os.Getenv("JWT_SECRET")
-
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
-	if err != nil || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid token",
-		})
+	if err := authenticate(c, tokenString); err != nil {
+		c.Locals("role", "guest")
 	}
+	return c.Next()
+}
+
+var errInvalidClaims = errors.New("invalid token claims")
 
-	claims, ok := token.Claims.(jwt.MapClaims)
+// authenticate verifies the access token against the user's token version and
+// session, then sets userid, sessionid and role. The role comes from the
+// database so role changes apply immediately.
+func authenticate(c *fiber.Ctx, tokenString string) error {
+	claims, err := module.ParseJWT(tokenString)
+	if err != nil {
+		return errInvalidClaims
+	}
+	userID, err := uuid.Parse(claimString(claims, "userid"))
+	if err != nil {
+		return errInvalidClaims
+	}
+	sessionID, err := uuid.Parse(claimString(claims, "sid"))
+	if err != nil {
+		return errInvalidClaims
+	}
+	version, ok := claims["ver"].(float64)
 	if !ok {
+		return errInvalidClaims
+	}
+
+	user, err := module.CheckAccess(db.DB, userID, sessionID, int(version))
+	if err != nil {
+		return err
+	}
+
+	c.Locals("role", user.Role)
+	c.Locals("userid", user.ID.String())
+	c.Locals("sessionid", sessionID.String())
+	c.Locals("mfa", user.MFAEnabledAt != nil)
+	return nil
+}
+
+// apiKeyAuth authenticates an API key and applies its rate limit. The key
+// is not a user: userid is empty, and scopes replaces the role's permissions.
+func apiKeyAuth(c *fiber.Ctx, raw string) error {
+	key, err := module.AuthenticateAPIKey(db.DB, raw)
+	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid token claims",
+			"error": "Invalid API key",
 		})
 	}
 
-	c.Locals("role", claims["role"])
-	c.Locals("userid", claims["userid"])
+	allowed, remaining, reset := module.APIKeyLimiter.Allow(key.ID.String(), key.RateLimit, time.Now())
+	c.Set("X-RateLimit-Limit", strconv.Itoa(key.RateLimit))
+	c.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	if !allowed {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(time.Until(reset).Seconds())+1))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": "API key rate limit exceeded",
+		})
+	}
 
+	c.Locals("role", "")
+	c.Locals("userid", "")
+	c.Locals("apikeyid", key.ID.String())
+	c.Locals("scopes", key.ScopeList())
 	return c.Next()
 }
+
+// IsAPIKey reports whether the request was authenticated with an API key
+// rather than as a user.
+func IsAPIKey(c *fiber.Ctx) bool {
+	id, _ := c.Locals("apikeyid").(string)
+	return id != ""
+}
+
+func claimString(claims jwt.MapClaims, key string) string {
+	s, _ := claims[key].(string)
+	return s
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"strings"
+
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HasPermission reports whether the caller may use perm: through the scopes
+// of an API key, or else through the user's role.
+func HasPermission(c *fiber.Ctx, perm string) bool {
+	if scopes, ok := c.Locals("scopes").([]string); ok {
+		for _, scope := range scopes {
+			if scope == perm {
+				return true
+			}
+		}
+		return false
+	}
+	role, _ := c.Locals("role").(string)
+	return models.HasPermission(role, perm)
+}
+
+// RequirePermission allows the request when the caller holds every
+// listed permission. It must run after Auth.
+func RequirePermission(perms ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, _ := c.Locals("role").(string)
+		if missingMFA(c, role) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Two-factor authentication is required for staff accounts",
+			})
+		}
+		for _, perm := range perms {
+			if !HasPermission(c, perm) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": "Access denied: missing permission " + perm,
+				})
+			}
+		}
+		return c.Next()
+	}
+}
+
+// RequireAnyPermission allows the request when the caller holds at least
+// one of the listed permissions. Handlers narrow it down further.
+func RequireAnyPermission(perms ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, _ := c.Locals("role").(string)
+		if missingMFA(c, role) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Two-factor authentication is required for staff accounts",
+			})
+		}
+		for _, perm := range perms {
+			if HasPermission(c, perm) {
+				return c.Next()
+			}
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied: requires one of " + strings.Join(perms, ", "),
+		})
+	}
+}
+
+// missingMFA reports whether a staff caller has no 2FA while
+// MFA_REQUIRED_FOR_STAFF is set. They can still sign in and enroll, just not
+// use staff endpoints.
+func missingMFA(c *fiber.Ctx, role string) bool {
+	enabled, _ := c.Locals("mfa").(bool)
+	return !enabled && module.MFARequired(role)
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxAddresses caps the saved addresses per user.
+const MaxAddresses = 20
+
+// AddressDetails is where and to whom a delivery goes. Orders keep a copy in
+// their shipping_ columns so later edits to the address do not change them.
+type AddressDetails struct {
+	Label     string   `json:"label" gorm:"type:varchar(50)"` // e.g. Home, Office
+	Recipient string   `json:"recipient" gorm:"type:varchar(100)"`
+	Phone     string   `json:"phone" gorm:"size:10"`
+	Line1     string   `json:"line1" gorm:"type:varchar(255)"`
+	Line2     string   `json:"line2" gorm:"type:varchar(255)"`
+	District  string   `json:"district" gorm:"type:varchar(100)"`
+	Province  string   `json:"province" gorm:"type:varchar(100)"`
+	Postcode  string   `json:"postcode" gorm:"type:varchar(10)"`
+	Lat       *float64 `json:"lat"`
+	Lng       *float64 `json:"lng"`
+}
+
+type Address struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement"`
+	UserID         uuid.UUID `gorm:"type:uuid;not null;index"`
+	AddressDetails `gorm:"embedded"`
+	IsDefault      bool `gorm:"not null;default:false"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
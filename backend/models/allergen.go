@@ -0,0 +1,178 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProductAllergens flags the common allergens declared on a product label.
+type ProductAllergens struct {
+	Gluten    bool `gorm:"not null;default:false"`
+	Dairy     bool `gorm:"not null;default:false"`
+	Eggs      bool `gorm:"not null;default:false"`
+	TreeNuts  bool `gorm:"not null;default:false"`
+	Peanuts   bool `gorm:"not null;default:false"`
+	Soy       bool `gorm:"not null;default:false"`
+	Sesame    bool `gorm:"not null;default:false"`
+	Fish      bool `gorm:"not null;default:false"`
+	Shellfish bool `gorm:"not null;default:false"`
+	Sulphites bool `gorm:"not null;default:false"`
+}
+
+// DietaryLabels are the diets a product is certified or declared suitable for.
+type DietaryLabels struct {
+	Vegan      bool `gorm:"not null;default:false"`
+	Vegetarian bool `gorm:"not null;default:false"`
+	Halal      bool `gorm:"not null;default:false"`
+	GlutenFree bool `gorm:"not null;default:false"`
+}
+
+// NutritionFacts are per serving values. Nil means not declared.
+type NutritionFacts struct {
+	ServingSize   string   `json:"serving_size,omitempty" gorm:"type:varchar(64)"`
+	Calories      *float64 `json:"calories,omitempty"`
+	Fat           *float64 `json:"fat_g,omitempty"`
+	SaturatedFat  *float64 `json:"saturated_fat_g,omitempty"`
+	Carbohydrates *float64 `json:"carbohydrates_g,omitempty"`
+	Sugars        *float64 `json:"sugars_g,omitempty"`
+	Protein       *float64 `json:"protein_g,omitempty"`
+	Sodium        *float64 `json:"sodium_mg,omitempty"`
+}
+
+// AllergenNames lists the allergen keys accepted by the API, in display order.
+var AllergenNames = []string{"gluten", "dairy", "eggs", "tree_nuts", "peanuts", "soy", "sesame", "fish", "shellfish", "sulphites"}
+
+// DietaryNames lists the dietary label keys accepted by the API.
+var DietaryNames = []string{"vegan", "vegetarian", "halal", "gluten_free"}
+
+func (a *ProductAllergens) field(name string) *bool {
+	switch name {
+	case "gluten":
+		return &a.Gluten
+	case "dairy":
+		return &a.Dairy
+	case "eggs":
+		return &a.Eggs
+	case "tree_nuts":
+		return &a.TreeNuts
+	case "peanuts":
+		return &a.Peanuts
+	case "soy":
+		return &a.Soy
+	case "sesame":
+		return &a.Sesame
+	case "fish":
+		return &a.Fish
+	case "shellfish":
+		return &a.Shellfish
+	case "sulphites":
+		return &a.Sulphites
+	}
+	return nil
+}
+
+func (d *DietaryLabels) field(name string) *bool {
+	switch name {
+	case "vegan":
+		return &d.Vegan
+	case "vegetarian":
+		return &d.Vegetarian
+	case "halal":
+		return &d.Halal
+	case "gluten_free":
+		return &d.GlutenFree
+	}
+	return nil
+}
+
+// List returns the keys of the allergens that are present.
+func (a ProductAllergens) List() []string {
+	list := []string{}
+	for _, name := range AllergenNames {
+		if *a.field(name) {
+			list = append(list, name)
+		}
+	}
+	return list
+}
+
+// List returns the keys of the dietary labels that apply.
+func (d DietaryLabels) List() []string {
+	list := []string{}
+	for _, name := range DietaryNames {
+		if *d.field(name) {
+			list = append(list, name)
+		}
+	}
+	return list
+}
+
+// ParseAllergens builds allergen flags from API keys. "nuts" is accepted as
+// shorthand for both tree nuts and peanuts.
+func ParseAllergens(names []string) (ProductAllergens, error) {
+	var a ProductAllergens
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "nuts" {
+			a.TreeNuts, a.Peanuts = true, true
+			continue
+		}
+		f := a.field(name)
+		if f == nil {
+			return a, fmt.Errorf("unknown allergen %q", name)
+		}
+		*f = true
+	}
+	return a, nil
+}
+
+// ParseDietaryLabels builds dietary labels from API keys.
+func ParseDietaryLabels(names []string) (DietaryLabels, error) {
+	var d DietaryLabels
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		f := d.field(name)
+		if f == nil {
+			return d, fmt.Errorf("unknown dietary label %q", name)
+		}
+		*f = true
+	}
+	return d, nil
+}
+
+// AllergenColumns maps an allergen key to its products column(s).
+func AllergenColumns(name string) ([]string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "nuts" {
+		return []string{"allergen_tree_nuts", "allergen_peanuts"}, true
+	}
+	var a ProductAllergens
+	if a.field(name) == nil {
+		return nil, false
+	}
+	return []string{"allergen_" + name}, true
+}
+
+// DietaryColumn maps a dietary label key to its products column.
+func DietaryColumn(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	var d DietaryLabels
+	if d.field(name) == nil {
+		return "", false
+	}
+	return "diet_" + name, true
+}
+
+// CheckDietaryConflicts rejects labels that contradict the declared allergens.
+func CheckDietaryConflicts(a ProductAllergens, d DietaryLabels) error {
+	if d.GlutenFree && a.Gluten {
+		return fmt.Errorf("gluten_free conflicts with gluten allergen")
+	}
+	if d.Vegan && (a.Dairy || a.Eggs || a.Fish || a.Shellfish) {
+		return fmt.Errorf("vegan conflicts with animal derived allergens")
+	}
+	if d.Vegetarian && (a.Fish || a.Shellfish) {
+		return fmt.Errorf("vegetarian conflicts with fish or shellfish allergens")
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey lets a script or device call the API without a login. The key is
+// "bpk_<prefix>_<secret>"; the prefix identifies it in lists and lookups and
+// only the SHA-256 of the secret is stored. Scopes are permissions, stored
+// comma separated.
+type APIKey struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Name       string    `gorm:"type:varchar(100);not null"`
+	Prefix     string    `gorm:"type:varchar(16);not null;uniqueIndex"`
+	KeyHash    string    `gorm:"type:char(64);not null"`
+	Scopes     string    `gorm:"type:text;not null"`
+	RateLimit  int       `gorm:"not null"` // requests per minute
+	CreatedBy  uuid.UUID `gorm:"type:uuid;not null"`
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+}
+
+// ScopeList returns the key's permissions.
+func (k *APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// Active reports whether the key may still be used.
+func (k *APIKey) Active() bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt))
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditLog records who changed what. Before and After hold JSON snapshots of
+// the affected fields so the change can be reviewed or reverted by hand.
+type AuditLog struct {
+	ID      uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	ActorID *uuid.UUID `json:"actor_id" gorm:"type:uuid;index"`
+	// APIKeyID is set instead of ActorID for changes made with an API key
+	APIKeyID   *uuid.UUID `json:"api_key_id,omitempty" gorm:"type:uuid;index"`
+	Action     string     `json:"action" gorm:"type:varchar(64);not null;index"`
+	EntityType string     `json:"entity_type" gorm:"type:varchar(64);not null;index:idx_audit_entity"`
+	EntityID   string     `json:"entity_id" gorm:"type:varchar(64);index:idx_audit_entity"`
+	Before     string     `json:"before,omitempty" gorm:"type:text"`
+	After      string     `json:"after,omitempty" gorm:"type:text"`
+	IP         string     `json:"ip,omitempty" gorm:"type:varchar(64)"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"index"`
+}
@@ -1,9 +1,13 @@
 package models
 
+import "math"
+
 func (user *User) ToResponse() UserResponse {
 	resp := UserResponse{
 		UserID:      user.ID,
 		Role:        user.Role,
+		Permissions: RolePermissions[user.Role],
+		MFAEnabled:  user.MFAEnabledAt != nil,
 		Name:        user.Name,
 		Username:    user.Username,
 		Place:       user.Place,
@@ -14,12 +18,22 @@ func (user *User) ToResponse() UserResponse {
 
 func (img *Image) ToResponse() ImageResponse {
 	resp := ImageResponse{
-		ID: img.ID,
+		ID:        img.ID,
+		Position:  img.Position,
+		AltText:   img.AltText,
+		IsPrimary: img.IsPrimary,
+		Status:    img.Status,
 	}
 
 	if img.PublicURL != nil && *img.PublicURL != "" {
 		resp.PublicURL = img.PublicURL
 	}
+	if img.ThumbnailURL != nil && *img.ThumbnailURL != "" {
+		resp.ThumbnailURL = img.ThumbnailURL
+	}
+	if img.MediumURL != nil && *img.MediumURL != "" {
+		resp.MediumURL = img.MediumURL
+	}
 	return resp
 }
 
@@ -29,16 +43,32 @@ func (p *Product) ToResponse() ProductResponse {
 		images[i] = img.ToResponse()
 	}
 
-	return ProductResponse{
-		ID:          p.ID,
-		Name:        p.Name,
-		Description: p.Description,
-		Tag:         p.Tag,
-		Price:       p.Price,
-		Stock:       p.Stock,
-		IsActive:    p.IsActive,
-		Images:      images,
+	resp := ProductResponse{
+		ID:           p.ID,
+		Name:         p.Name,
+		Description:  p.Description,
+		Tag:          p.Tag,
+		Price:        p.Price,
+		Stock:        p.Stock,
+		IsActive:     p.IsActive,
+		Images:       images,
+		SKU:          p.SKU,
+		Barcode:      p.Barcode,
+		PLU:          p.PLU,
+		SoldByWeight: p.SoldByWeight,
+		Allergens:    p.Allergens.List(),
+		Dietary:      p.Dietary.List(),
+		Ingredients:  p.Ingredients,
+		ShelfLife:    p.ShelfLife,
+
+		EarlyAccessUntil: p.EarlyAccessUntil,
+	}
+
+	if p.Nutrition != (NutritionFacts{}) {
+		nutrition := p.Nutrition
+		resp.Nutrition = &nutrition
 	}
+	return resp
 }
 
 func (order *Order) ToResponse() OrderResponse {
@@ -48,11 +78,27 @@ func (order *Order) ToResponse() OrderResponse {
 		Status:    order.Status,
 		Items:     order.Items,
 		CreatedAt: order.CreatedAt,
+
+		PointsRedeemed: order.PointsRedeemed,
+		PointsDiscount: order.PointsDiscount,
+		DeliveryFee:    order.DeliveryFee,
+
+		AmountDue: math.Round((order.Total-order.TenderTotal)*100) / 100,
+		Payments:  order.Payments,
+	}
+	if order.Shipping.Recipient != "" {
+		shipping := order.Shipping
+		resp.ShippingAddress = &shipping
 	}
 	return resp
 }
 
 func (c *Cart) ToResponse() []CartItemResponse {
+	return c.ToResponseFor(TierPricing{})
+}
+
+// ToResponseFor prices the cart for a member of a tier.
+func (c *Cart) ToResponseFor(pricing TierPricing) []CartItemResponse {
 	var items []CartItemResponse
 
 	for _, item := range c.Items {
@@ -64,7 +110,7 @@ func (c *Cart) ToResponse() []CartItemResponse {
 		if item.Product != nil {
 			resp.ProductName = item.Product.Name
 			resp.Price = item.Product.Price
-			resp.SalePrice = item.Product.FinalPrice()
+			resp.SalePrice = item.Product.MemberPrice(pricing)
 
 			if len(item.Product.Images) > 0 {
 				images := make([]ImageResponse, len(item.Product.Images))
@@ -101,3 +147,39 @@ func (p *Promotion) ToResponse() PromotionResponse {
 		IsActive:    p.IsActive,
 	}
 }
+
+func (s *Session) ToResponse() SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+		CreatedAt:  s.CreatedAt,
+		LastUsedAt: s.LastUsedAt,
+		ExpiresAt:  s.ExpiresAt,
+	}
+}
+
+func (k *APIKey) ToResponse() APIKeyResponse {
+	return APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.ScopeList(),
+		RateLimit:  k.RateLimit,
+		CreatedBy:  k.CreatedBy,
+		CreatedAt:  k.CreatedAt,
+		LastUsedAt: k.LastUsedAt,
+		ExpiresAt:  k.ExpiresAt,
+		RevokedAt:  k.RevokedAt,
+		Active:     k.Active(),
+	}
+}
+
+func (a *Address) ToResponse() AddressResponse {
+	return AddressResponse{
+		ID:             a.ID,
+		AddressDetails: a.AddressDetails,
+		IsDefault:      a.IsDefault,
+		UpdatedAt:      a.UpdatedAt,
+	}
+}
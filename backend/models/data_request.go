@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DataRequestExport   = "export"
+	DataRequestDeletion = "deletion"
+
+	DataRequestPending   = "pending"
+	DataRequestCompleted = "completed"
+	DataRequestRejected  = "rejected"
+)
+
+// DataRequest is a customer's PDPA request for their data or its deletion.
+// Self-service requests are completed on the spot and kept as a record; the
+// rest wait in the admin queue until DueAt.
+type DataRequest struct {
+	ID          uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID      uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	Type        string     `json:"type" gorm:"type:varchar(20);not null;check:type IN ('export','deletion')"`
+	Status      string     `json:"status" gorm:"type:varchar(20);not null;index;check:status IN ('pending','completed','rejected')"`
+	Note        string     `json:"note,omitempty" gorm:"type:text"`
+	DueAt       time.Time  `json:"due_at" gorm:"not null;index"`
+	CompletedAt *time.Time `json:"completed_at"`
+	HandledBy   *uuid.UUID `json:"handled_by" gorm:"type:uuid"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
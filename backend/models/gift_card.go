@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Statuses of a GiftCard. Cards sold over the counter are issued inactive
+// and activated once paid for.
+const (
+	GiftCardInactive = "inactive"
+	GiftCardActive   = "active"
+	GiftCardVoid     = "void"
+)
+
+// Kinds of GiftCardTransaction and StoreCreditEntry.
+const (
+	StoredValueIssue  = "issue"
+	StoredValueRedeem = "redeem" // spent as tender on an order
+	StoredValueRefund = "refund" // tender given back on cancellation
+	StoredValueVoid   = "void"   // removed by an admin
+)
+
+// GiftCard is a stored value card identified by a 16 digit code whose last
+// digit is a Luhn check digit.
+type GiftCard struct {
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Code         string     `json:"code" gorm:"type:varchar(16);not null;uniqueIndex"`
+	InitialValue float64    `json:"initial_value" gorm:"not null"`
+	Balance      float64    `json:"balance" gorm:"not null"`
+	Status       string     `json:"status" gorm:"type:varchar(20);not null;default:'inactive';check:status IN ('inactive','active','void')"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	Note         string     `json:"note,omitempty" gorm:"type:text"`
+	IssuedBy     *uuid.UUID `json:"issued_by,omitempty" gorm:"type:uuid"`
+	ActivatedAt  *time.Time `json:"activated_at,omitempty"`
+	VoidedAt     *time.Time `json:"voided_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Expired reports whether the card is past its expiry at now.
+func (g *GiftCard) Expired(now time.Time) bool {
+	return g.ExpiresAt != nil && !now.Before(*g.ExpiresAt)
+}
+
+// GiftCardTransaction is one line of a card's ledger. Amount is signed and
+// BalanceAfter is the card balance once it was applied.
+type GiftCardTransaction struct {
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	GiftCardID   uint       `json:"gift_card_id" gorm:"not null;index"`
+	OrderID      *string    `json:"order_id,omitempty" gorm:"index"`
+	Kind         string     `json:"kind" gorm:"type:varchar(20);not null;check:kind IN ('issue','redeem','refund','void')"`
+	Amount       float64    `json:"amount" gorm:"not null"`
+	BalanceAfter float64    `json:"balance_after" gorm:"not null"`
+	Note         string     `json:"note,omitempty" gorm:"type:text"`
+	ActorID      *uuid.UUID `json:"actor_id,omitempty" gorm:"type:uuid"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// StoreCreditEntry is one line of a user's store credit wallet; the balance
+// is the sum of Amount.
+type StoreCreditEntry struct {
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID       uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	OrderID      *string    `json:"order_id,omitempty" gorm:"index"`
+	Kind         string     `json:"kind" gorm:"type:varchar(20);not null;check:kind IN ('issue','redeem','refund','void')"`
+	Amount       float64    `json:"amount" gorm:"not null"`
+	BalanceAfter float64    `json:"balance_after" gorm:"not null"`
+	Reason       string     `json:"reason,omitempty" gorm:"type:text"`
+	ActorID      *uuid.UUID `json:"actor_id,omitempty" gorm:"type:uuid"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
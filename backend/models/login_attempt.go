@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LoginAttempt is the Postgres backed failure counter for a login key, e.g.
+// "user:alice" or "ip:203.0.113.5".
+type LoginAttempt struct {
+	Key           string `gorm:"primaryKey;type:varchar(255)"`
+	Failures      int    `gorm:"not null;default:0"`
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of LoyaltyEntry.
+const (
+	LoyaltyEarn    = "earn"    // order reached the earn status
+	LoyaltyRedeem  = "redeem"  // spent as a checkout discount
+	LoyaltyReverse = "reverse" // earned points taken back on cancellation
+	LoyaltyRefund  = "refund"  // redeemed points given back on cancellation
+	LoyaltyExpire  = "expire"
+	LoyaltyAdjust  = "adjust" // by an admin, with a reason
+)
+
+// LoyaltyEntry is one line of a user's points ledger; the balance is the sum
+// of Points. Credits track how much of them is left to spend in Remaining and
+// are used oldest first, so the oldest points are the ones that expire.
+type LoyaltyEntry struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	OrderID   *string    `json:"order_id,omitempty" gorm:"index"`
+	Kind      string     `json:"kind" gorm:"type:varchar(20);not null;check:kind IN ('earn','redeem','reverse','refund','expire','adjust')"`
+	Points    int        `json:"points" gorm:"not null"`
+	Remaining int        `json:"-" gorm:"not null;default:0"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	Reason    string     `json:"reason,omitempty" gorm:"type:text"`
+	ActorID   *uuid.UUID `json:"actor_id,omitempty" gorm:"type:uuid"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// LoyaltyRule multiplies the points earned on items. Without a category it
+// applies to every item; without dates it always applies, with them it is a
+// bonus campaign. An item uses the largest multiplier that matches it.
+type LoyaltyRule struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name       string     `json:"name" gorm:"type:varchar(255);not null"`
+	Category   string     `json:"category" gorm:"type:varchar(255)"`
+	Multiplier float64    `json:"multiplier" gorm:"not null"`
+	StartsAt   *time.Time `json:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at"`
+	IsActive   bool       `json:"is_active" gorm:"not null;default:true"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Applies reports whether the rule covers an item of category at t.
+func (r *LoyaltyRule) Applies(category string, t time.Time) bool {
+	return r.IsActive &&
+		(r.Category == "" || r.Category == category) &&
+		(r.StartsAt == nil || !t.Before(*r.StartsAt)) &&
+		(r.EndsAt == nil || t.Before(*r.EndsAt))
+}
@@ -8,28 +8,77 @@ import (
 	"gorm.io/gorm"
 )
 
+// Sales channels of an Order.
+const (
+	ChannelOnline = "online"
+	ChannelPOS    = "pos"
+)
+
 type Order struct {
-	ID          string    `gorm:"primaryKey"`
-	UserID      uuid.UUID `gorm:"not null;index"`
+	ID string `gorm:"primaryKey"`
+	// UserID is the customer; counter sales may have none
+	UserID      *uuid.UUID `gorm:"index"`
 	Total       float64
 	PaymentSlip string `gorm:"type:text"`
-	Status      string `gorm:"type:varchar(20);check:status IN ('pending','confirmed','shipping','delivered')"`
+	Status      string `gorm:"type:varchar(20);check:status IN ('pending','confirmed','shipping','delivered','completed','cancelled')"`
+	Channel     string `gorm:"type:varchar(10);not null;default:'online';check:channel IN ('online','pos')"`
+	// CashierID is the staff member who rang up a counter sale, during ShiftID
+	CashierID *uuid.UUID `gorm:"type:uuid"`
+	ShiftID   *uint      `gorm:"index"`
+	// Subtotal is the item total; Total is Subtotal less PointsDiscount.
+	// Orders placed before points have no Subtotal.
+	Subtotal       float64
+	PointsRedeemed int `gorm:"not null;default:0"`
+	PointsDiscount float64
+	DeliveryFee    float64
+	// ItemDiscount is what promotions and the tier took off list prices
+	ItemDiscount float64 `gorm:"not null;default:0"`
+	// TenderTotal is the part of Total already paid: gift cards and store
+	// credit, and cash or card at the counter; the rest is paid by transfer
+	TenderTotal float64 `gorm:"not null;default:0"`
+	// Shipping is the delivery address chosen at checkout, empty for pickup
+	Shipping AddressDetails `gorm:"embedded;embeddedPrefix:shipping_"`
 
-	Items     []OrderItem `gorm:"foreignKey:OrderID;references:ID;constraint:OnDelete:CASCADE"`
+	Items     []OrderItem    `gorm:"foreignKey:OrderID;references:ID;constraint:OnDelete:CASCADE"`
+	Payments  []OrderPayment `gorm:"foreignKey:OrderID;references:ID;constraint:OnDelete:CASCADE"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
 type OrderItem struct {
 	ID        uint   `gorm:"primaryKey;autoIncrement" json:"id"`
-	OrderID   string `gorm:"not null;index:idx_order_product,unique" json:"order_id"`
-	ProductID uint   `gorm:"not null;index:idx_order_product,unique" json:"product_id"`
+	OrderID   string `gorm:"not null;index:idx_order_product" json:"order_id"`
+	ProductID uint   `gorm:"not null;index:idx_order_product" json:"product_id"`
 	Quantity  int    `gorm:"not null" json:"quantity"`
 
 	Name        string  `json:"name"`
 	Description string  `json:"description"`
 	Tag         string  `json:"tag"`
-	Price       float64 `json:"price"`
+	Price       float64 `json:"price"` // per unit, modifiers included
+	// Modifiers lists the counter sale options, e.g. "Extra cream (+20.00)"
+	Modifiers string `json:"modifiers,omitempty" gorm:"type:text"`
+}
+
+// Tender methods of OrderPayment.
+const (
+	TenderGiftCard    = "gift_card"
+	TenderStoreCredit = "store_credit"
+	TenderCash        = "cash" // counter sales only
+	TenderCard        = "card" // counter sales only
+)
+
+// OrderPayment is one tender used on an order. Cancelling the order gives it
+// back and sets RefundedAt.
+type OrderPayment struct {
+	ID         uint    `json:"id" gorm:"primaryKey;autoIncrement"`
+	OrderID    string  `json:"order_id" gorm:"not null;index"`
+	Method     string  `json:"method" gorm:"type:varchar(20);not null"`
+	GiftCardID *uint   `json:"gift_card_id,omitempty" gorm:"index"`
+	Reference  string  `json:"reference,omitempty" gorm:"type:varchar(50)"` // masked gift card code
+	Amount     float64 `json:"amount" gorm:"not null"`
+
+	RefundedAt *time.Time `json:"refunded_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
 }
 
 func (o *Order) BeforeCreate(tx *gorm.DB) (err error) {
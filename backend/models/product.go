@@ -8,21 +8,62 @@ import (
 
 type Product struct {
 	gorm.Model
-	Name        string      `json:"name" gorm:"unique;type:varchar(255);not null"`
-	Description string      `json:"description" gorm:"type:text"`
-	Tag         string      `json:"tag" gorm:"not null"`
-	Price       float64     `json:"price" gorm:"not null"`
-	Stock       int         `json:"stock" gorm:"not null"`
-	IsActive    bool        `json:"is_active" gorm:"default:true"`
-	Images      []Image     `json:"images" gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE"`
-	Promotions  []Promotion `json:"promotions" gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE"`
+	Name        string  `json:"name" gorm:"unique;type:varchar(255);not null"`
+	Description string  `json:"description" gorm:"type:text"`
+	Tag         string  `json:"tag" gorm:"not null"`
+	Price       float64 `json:"price" gorm:"not null"`
+	Stock       int     `json:"stock" gorm:"not null"`
+	IsActive    bool    `json:"is_active" gorm:"default:true"`
+
+	// SKU is the internal stock code, Barcode a normalized 13 digit EAN/UPC.
+	// Items sold by weight use a 5 digit PLU embedded in in-store barcodes
+	// and Price is then per kilogram.
+	SKU          *string `json:"sku" gorm:"type:varchar(64);uniqueIndex"`
+	Barcode      *string `json:"barcode" gorm:"type:varchar(13);uniqueIndex"`
+	PLU          *string `json:"plu" gorm:"type:varchar(5);uniqueIndex"`
+	SoldByWeight bool    `json:"sold_by_weight" gorm:"not null;default:false"`
+
+	Allergens   ProductAllergens `json:"allergens" gorm:"embedded;embeddedPrefix:allergen_"`
+	Dietary     DietaryLabels    `json:"dietary" gorm:"embedded;embeddedPrefix:diet_"`
+	Ingredients string           `json:"ingredients" gorm:"type:text"`
+	Nutrition   NutritionFacts   `json:"nutrition" gorm:"embedded;embeddedPrefix:nutrition_"`
+	ShelfLife   string           `json:"shelf_life" gorm:"type:text"`
+
+	// EarlyAccessUntil keeps a seasonal product to early access tiers until then
+	EarlyAccessUntil *time.Time `json:"early_access_until"`
+
+	Images     []Image     `json:"images" gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE"`
+	Promotions []Promotion `json:"promotions" gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE"`
 }
 
+// Image is a product picture. New rows start as pending until the upload is
+// confirmed, which validates the file and generates the renditions.
 type Image struct {
-	ID        uint
-	ProductID uint
-	FilePath  string
-	PublicURL *string
+	ID          uint
+	ProductID   uint `gorm:"index"`
+	FilePath    string
+	PublicURL   *string
+	Position    int    `gorm:"not null;default:0"`
+	AltText     string `gorm:"type:varchar(255)"`
+	IsPrimary   bool   `gorm:"not null;default:false"`
+	Status      string `gorm:"type:varchar(16);not null;default:ready"`
+	ContentType string `gorm:"type:varchar(64)"`
+	Size        int64
+
+	ThumbnailPath string
+	ThumbnailURL  *string
+	MediumPath    string
+	MediumURL     *string
+}
+
+const (
+	ImageStatusPending = "pending"
+	ImageStatusReady   = "ready"
+)
+
+// ImagesByPosition orders preloaded images the way they are displayed.
+func ImagesByPosition(db *gorm.DB) *gorm.DB {
+	return db.Order("images.position ASC, images.id ASC")
 }
 
 type Promotion struct {
@@ -37,7 +78,29 @@ type Promotion struct {
 }
 
 func (p *Product) FinalPrice() float64 {
-	price := p.Price
+	return p.Price - (p.Price * p.promotionDiscount() / 100)
+}
+
+// MemberPrice is FinalPrice with the member's tier discount applied.
+func (p *Product) MemberPrice(t TierPricing) float64 {
+	promo := p.promotionDiscount()
+	if t.DiscountPercent <= 0 {
+		return p.Price - (p.Price * promo / 100)
+	}
+	if t.Stack {
+		price := p.Price - (p.Price * promo / 100)
+		return price - (price * t.DiscountPercent / 100)
+	}
+	return p.Price - (p.Price * max(promo, t.DiscountPercent) / 100)
+}
+
+// EarlyAccess reports whether the product is still reserved for early access tiers.
+func (p *Product) EarlyAccess(now time.Time) bool {
+	return p.EarlyAccessUntil != nil && now.Before(*p.EarlyAccessUntil)
+}
+
+// promotionDiscount is the largest discount percentage of the running promotions.
+func (p *Product) promotionDiscount() float64 {
 	maxDiscount := 0.0
 	now := time.Now()
 	for _, promo := range p.Promotions {
@@ -54,5 +117,5 @@ func (p *Product) FinalPrice() float64 {
 			maxDiscount = promo.Discount
 		}
 	}
-	return price - (price * maxDiscount / 100)
+	return maxDiscount
 }
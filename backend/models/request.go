@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type FormRequest struct {
 	Username string `json:"username"`
@@ -8,6 +12,7 @@ type FormRequest struct {
 }
 
 type FormSetting struct {
+	Name        *string `json:"name"`
 	PhoneNumber *string `json:"phone_number"`
 	Place       *string `json:"place"`
 }
@@ -23,11 +28,29 @@ type BodyProductRequest struct {
 	Price       float64 `json:"price" gorm:"not null"`
 	Stock       int     `json:"quantity" gorm:"not null"`
 	IsActive    bool    `json:"is_active" gorm:"default:true"`
+
+	SKU          *string `json:"sku"`
+	Barcode      *string `json:"barcode"`
+	PLU          *string `json:"plu"`
+	SoldByWeight bool    `json:"sold_by_weight"`
+
+	Allergens   []string        `json:"allergens"`
+	Dietary     []string        `json:"dietary"`
+	Ingredients string          `json:"ingredients"`
+	Nutrition   *NutritionFacts `json:"nutrition"`
+	ShelfLife   string          `json:"shelf_life"`
+
+	EarlyAccessUntil *time.Time `json:"early_access_until"`
 }
 type ImageIDsRequest struct {
 	IDs []uint `json:"ids"`
 }
 
+type BodyImageUpdateRequest struct {
+	AltText   *string `json:"alt_text"`
+	IsPrimary *bool   `json:"is_primary"`
+}
+
 type BodyPromotionRequest struct {
 	ProductID   uint      `json:"product_id"`
 	Name        string    `json:"name"`
@@ -41,3 +64,195 @@ type BodyPromotionRequest struct {
 type BodyUpdateOrder struct {
 	Status string `json:"status"`
 }
+
+type BulkProductSelector struct {
+	IDs      []uint `json:"ids"`
+	Category string `json:"category"`
+	Query    string `json:"q"`
+	All      bool   `json:"all"`
+}
+
+type BulkProductOperation struct {
+	Field  string  `json:"field"`  // price | stock | is_active
+	Type   string  `json:"type"`   // set | increase | decrease | toggle
+	Amount float64 `json:"amount"` // used by price and stock
+	Unit   string  `json:"unit"`   // absolute (default) | percent
+	Active *bool   `json:"active"` // used by is_active with type set
+}
+
+type BodyBulkProductRequest struct {
+	Selector  BulkProductSelector  `json:"selector"`
+	Operation BulkProductOperation `json:"operation"`
+	DryRun    bool                 `json:"dry_run"`
+}
+
+type BodyUpdateRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type BodyUserStatusRequest struct {
+	Disabled bool `json:"disabled"`
+}
+
+type BodyAdminResetPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+type BodyRefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type BodyChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type BodyPasswordResetRequest struct {
+	Username string `json:"username"`
+}
+
+type BodyPasswordResetConfirm struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type BodyMFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+type BodyMFADisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type BodyLoginMFARequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"` // TOTP or backup code
+}
+
+type BodyAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	RateLimit int        `json:"rate_limit"` // requests per minute, default 120
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type BodyAddressRequest struct {
+	AddressDetails
+	IsDefault bool `json:"is_default"`
+}
+
+type BodyCheckoutRequest struct {
+	// AddressID picks a saved address; without it the default address is
+	// used, and with no saved address the order is for pickup
+	AddressID *uint `json:"address_id"`
+	// RedeemPoints spends loyalty points as a discount, up to the redeem cap
+	RedeemPoints int `json:"redeem_points"`
+	// StoreCredit pays up to this many baht from the store credit wallet,
+	// then GiftCards pay what is left, in order
+	StoreCredit float64  `json:"store_credit"`
+	GiftCards   []string `json:"gift_cards"`
+}
+
+type BodyDeleteAccountRequest struct {
+	Password string `json:"password"`
+	Reason   string `json:"reason"`
+}
+
+type BodyDataRequestRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+	Type   string    `json:"type"` // export | deletion
+	Note   string    `json:"note"`
+}
+
+type BodyDataRequestResolve struct {
+	Note string `json:"note"`
+}
+
+type BodyLoyaltyRuleRequest struct {
+	Name       string     `json:"name"`
+	Category   string     `json:"category"` // empty for every category
+	Multiplier float64    `json:"multiplier"`
+	StartsAt   *time.Time `json:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at"`
+	IsActive   bool       `json:"is_active"`
+}
+
+type BodyTierRequest struct {
+	Name            string  `json:"name"`
+	MinSpend        float64 `json:"min_spend"`        // 12 month spend in baht
+	DiscountPercent float64 `json:"discount_percent"` // 0 to 100
+	FreeDelivery    bool    `json:"free_delivery"`
+	EarlyAccess     bool    `json:"early_access"`
+}
+
+type BodyLoyaltyAdjustRequest struct {
+	Points int    `json:"points"` // negative to remove
+	Reason string `json:"reason"`
+}
+
+type BodyGiftCardRequest struct {
+	Value     float64    `json:"value"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	// Activate makes the card usable right away; otherwise it is activated
+	// once paid for
+	Activate bool   `json:"activate"`
+	Note     string `json:"note"`
+}
+
+type BodyStoredValueVoidRequest struct {
+	Amount float64 `json:"amount"` // store credit only; 0 removes the whole balance
+	Reason string  `json:"reason"`
+}
+
+type BodyStoreCreditRequest struct {
+	Amount  float64 `json:"amount"`
+	Reason  string  `json:"reason"`
+	OrderID *string `json:"order_id"` // the refunded order, if any
+}
+
+type BodyPOSSaleRequest struct {
+	Items []BodyPOSLine `json:"items"`
+	// CustomerPhone attaches a member, who gets tier pricing and earns points
+	CustomerPhone string `json:"customer_phone"`
+	// PaymentMethod pays what store credit and gift cards leave: cash (default) or card
+	PaymentMethod string   `json:"payment_method"`
+	CashReceived  float64  `json:"cash_received"` // 0 for the exact amount
+	StoreCredit   float64  `json:"store_credit"`  // needs a customer
+	GiftCards     []string `json:"gift_cards"`
+}
+
+// BodyPOSLine is one item of a counter sale, by product ID or scanned code.
+type BodyPOSLine struct {
+	ProductID uint          `json:"product_id"`
+	Code      string        `json:"code"` // barcode, in-store barcode or SKU
+	Quantity  int           `json:"quantity"`
+	Modifiers []POSModifier `json:"modifiers"`
+}
+
+// POSModifier is an option on a counter sale item, e.g. extra cream. Price is
+// added per unit and may be 0.
+type POSModifier struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+type BodyOpenShiftRequest struct {
+	OpeningFloat float64 `json:"opening_float"`
+	Register     string  `json:"register"`
+}
+
+type BodyCashMovementRequest struct {
+	Kind   string  `json:"kind"` // in | out
+	Amount float64 `json:"amount"`
+	Reason string  `json:"reason"`
+}
+
+type BodyCloseShiftRequest struct {
+	CountedCash float64 `json:"counted_cash"`
+	Note        string  `json:"note"`
+}
+
+type BodyVoidSaleRequest struct {
+	Reason string `json:"reason"`
+}
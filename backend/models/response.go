@@ -16,8 +16,14 @@ type UserResponse struct {
 	Name        *string   `json:"name"`
 	Username    string    `json:"username"`
 	Exp         *int64    `json:"exp"`
+	Permissions []string  `json:"permissions"`
+	MFAEnabled  bool      `json:"mfa_enabled"`
 	Place       *string   `json:"address,omitempty"`
 	PhoneNumber *string   `json:"phone,omitempty"`
+
+	// only set for clients that sent X-Auth-Transport: header
+	AccessToken  *string `json:"access_token,omitempty"`
+	RefreshToken *string `json:"refresh_token,omitempty"`
 }
 
 type ProductResponse struct {
@@ -26,9 +32,23 @@ type ProductResponse struct {
 	Description string          `json:"detail"`
 	Tag         string          `json:"category"`
 	Price       float64         `json:"price"`
+	MemberPrice *float64        `json:"member_price,omitempty"` // with the caller's tier discount, as in their cart
 	Stock       int             `json:"quantity"`
 	IsActive    bool            `json:"is_active"`
 	Images      []ImageResponse `json:"images,omitempty"`
+
+	SKU          *string `json:"sku,omitempty"`
+	Barcode      *string `json:"barcode,omitempty"`
+	PLU          *string `json:"plu,omitempty"`
+	SoldByWeight bool    `json:"sold_by_weight"`
+
+	Allergens   []string        `json:"allergens"`
+	Dietary     []string        `json:"dietary"`
+	Ingredients string          `json:"ingredients,omitempty"`
+	Nutrition   *NutritionFacts `json:"nutrition,omitempty"`
+	ShelfLife   string          `json:"shelf_life,omitempty"`
+
+	EarlyAccessUntil *time.Time `json:"early_access_until,omitempty"`
 }
 
 type ImagesArrayResponse struct {
@@ -36,9 +56,15 @@ type ImagesArrayResponse struct {
 }
 
 type ImageResponse struct {
-	ID        uint    `json:"id"`
-	PublicURL *string `json:"public_url,omitempty"`
-	UploadURL *string `json:"upload_url,omitempty"`
+	ID           uint    `json:"id"`
+	PublicURL    *string `json:"public_url,omitempty"`
+	UploadURL    *string `json:"upload_url,omitempty"`
+	ThumbnailURL *string `json:"thumbnail_url,omitempty"`
+	MediumURL    *string `json:"medium_url,omitempty"`
+	Position     int     `json:"position"`
+	AltText      string  `json:"alt_text"`
+	IsPrimary    bool    `json:"is_primary"`
+	Status       string  `json:"status"`
 }
 
 type CartItemResponse struct {
@@ -51,29 +77,51 @@ type CartItemResponse struct {
 }
 
 type CheckoutResponse struct {
-	Message string  `json:"message"`
-	OrderID string  `json:"order_id"`
-	Total   float64 `json:"total"`
-	Status  string  `json:"status"`
+	Message        string  `json:"message"`
+	OrderID        string  `json:"order_id"`
+	Subtotal       float64 `json:"subtotal"`
+	PointsRedeemed int     `json:"points_redeemed"`
+	PointsDiscount float64 `json:"points_discount"`
+	DeliveryFee    float64 `json:"delivery_fee"`
+	Total          float64 `json:"total"`
+	Status         string  `json:"status"`
+
+	Payments  []OrderPayment `json:"payments"`
+	AmountDue float64        `json:"amount_due"`
 }
 
 type OrderResponse struct {
 	OrderID   string  `json:"order_id"`
 	Total     float64 `json:"total"`
 	Status    string  `json:"status"`
-	PublicURL *string `json:"public_url,omitempty"`
+	SlipURL   *string `json:"slip_url,omitempty"`
 	UploadURL *string `json:"upload_url,omitempty"`
 
+	PointsRedeemed int     `json:"points_redeemed,omitempty"`
+	PointsDiscount float64 `json:"points_discount,omitempty"`
+	DeliveryFee    float64 `json:"delivery_fee,omitempty"`
+
+	// AmountDue is what is left to pay by transfer after the tenders
+	AmountDue float64        `json:"amount_due"`
+	Payments  []OrderPayment `json:"payments,omitempty"`
+
+	ShippingAddress *AddressDetails `json:"shipping_address,omitempty"`
+
 	CreatedAt time.Time `json:"create_at"`
 
 	Items []OrderItem `json:"items"`
 }
 
 type UploadOrderSlipResponse struct {
-	PublicURL string `json:"public_url"`
 	UploadURL string `json:"upload_url"`
 }
 
+type OrderSlipResponse struct {
+	OrderID   string    `json:"order_id"`
+	SlipURL   string    `json:"slip_url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 type PromotionResponse struct {
 	ID          uint      `json:"id"`
 	ProductID   uint      `json:"product_id"`
@@ -84,3 +132,322 @@ type PromotionResponse struct {
 	EndDate     time.Time `json:"end_date"`
 	IsActive    bool      `json:"is_active"`
 }
+
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+type ProductImportRowResult struct {
+	Line      int                    `json:"line"`
+	Name      string                 `json:"name"`
+	Action    string                 `json:"action"` // create | update | skip | error
+	ProductID uint                   `json:"product_id,omitempty"`
+	Changes   map[string]FieldChange `json:"changes,omitempty"`
+	Errors    []string               `json:"errors,omitempty"`
+}
+
+type ProductImportSummary struct {
+	Create int `json:"create"`
+	Update int `json:"update"`
+	Skip   int `json:"skip"`
+	Errors int `json:"errors"`
+}
+
+type ProductImportResponse struct {
+	DryRun  bool                     `json:"dry_run"`
+	Applied bool                     `json:"applied"`
+	Summary ProductImportSummary     `json:"summary"`
+	Rows    []ProductImportRowResult `json:"rows"`
+}
+
+type BulkProductChange struct {
+	ProductID uint        `json:"product_id"`
+	Name      string      `json:"name"`
+	Field     string      `json:"field"`
+	From      interface{} `json:"from"`
+	To        interface{} `json:"to"`
+}
+
+type BulkProductResponse struct {
+	DryRun  bool                `json:"dry_run"`
+	Matched int                 `json:"matched"`
+	Changed int                 `json:"changed"`
+	Changes []BulkProductChange `json:"changes"`
+}
+
+type AuditLogListResponse struct {
+	Data  []AuditLog `json:"data"`
+	Total int64      `json:"total"`
+	Page  int        `json:"page"`
+	Limit int        `json:"limit"`
+}
+
+type ProductLookupResponse struct {
+	Code     string          `json:"code"`
+	Match    string          `json:"match"` // barcode | sku | plu
+	Product  ProductResponse `json:"product"`
+	Quantity float64         `json:"quantity"`
+	Unit     string          `json:"unit"` // piece | kg
+	Price    float64         `json:"price"`
+}
+
+type StorageOrphan struct {
+	Bucket    string    `json:"bucket"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Deleted   bool      `json:"deleted"`
+	InGrace   bool      `json:"in_grace"` // younger than the grace period, kept
+}
+
+type DanglingImage struct {
+	ImageID   uint     `json:"image_id"`
+	ProductID uint     `json:"product_id"`
+	Missing   []string `json:"missing"` // referenced paths not found in storage
+}
+
+type StorageGCResponse struct {
+	DryRun         bool            `json:"dry_run"`
+	GraceHours     float64         `json:"grace_hours"`
+	Scanned        int             `json:"scanned"`
+	Orphans        []StorageOrphan `json:"orphans"`
+	Deleted        int             `json:"deleted"`
+	FreedBytes     int64           `json:"freed_bytes"`
+	DanglingImages []DanglingImage `json:"dangling_images"`
+	Errors         []string        `json:"errors,omitempty"`
+}
+
+type RoleResponse struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
+type AdminUserResponse struct {
+	UserResponse
+	CreatedAt     time.Time  `json:"created_at"`
+	DisabledAt    *time.Time `json:"disabled_at"`
+	OrderCount    int64      `json:"order_count"`
+	LifetimeSpend float64    `json:"lifetime_spend"`
+	LastOrderAt   *time.Time `json:"last_order_at,omitempty"`
+}
+
+type AdminUserListResponse struct {
+	Data  []AdminUserResponse `json:"data"`
+	Total int64               `json:"total"`
+	Page  int                 `json:"page"`
+	Limit int                 `json:"limit"`
+}
+
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+type MFAEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRCode     string `json:"qr_code"` // PNG data URI
+}
+
+type MFABackupCodesResponse struct {
+	BackupCodes []string `json:"backup_codes"`
+}
+
+// LoginMFAResponse is returned by login instead of a session when the user
+// has 2FA; post the token with a code to /user/login/mfa.
+type LoginMFAResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type APIKeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	RateLimit  int        `json:"rate_limit"`
+	CreatedBy  uuid.UUID  `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	Active     bool       `json:"active"`
+}
+
+// APIKeyCreatedResponse holds the only copy of the full key.
+type APIKeyCreatedResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+type AddressResponse struct {
+	ID uint `json:"id"`
+	AddressDetails
+	IsDefault bool      `json:"is_default"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ProfileResponse struct {
+	UserResponse
+	CreatedAt time.Time         `json:"created_at"`
+	Addresses []AddressResponse `json:"addresses"`
+	// Tier is nil below the lowest tier; TierSpend is the 12 month spend it is based on
+	Tier      *MembershipTier `json:"tier"`
+	TierSpend float64         `json:"tier_spend"`
+}
+
+type DataRequestResponse struct {
+	DataRequest
+	Username string `json:"username"`
+	Overdue  bool   `json:"overdue"`
+}
+
+type DataRequestListResponse struct {
+	Data  []DataRequestResponse `json:"data"`
+	Total int64                 `json:"total"`
+	Page  int                   `json:"page"`
+	Limit int                   `json:"limit"`
+}
+
+type DeleteAccountResponse struct {
+	Message string `json:"message"`
+	// Queued is set when open orders keep the request in the admin queue
+	Queued bool       `json:"queued"`
+	DueAt  *time.Time `json:"due_at,omitempty"`
+}
+
+type LoyaltyResponse struct {
+	Balance int `json:"balance"`
+	// PointValue is what one point takes off at checkout, in baht
+	PointValue     float64        `json:"point_value"`
+	RedeemCap      float64        `json:"redeem_cap"`
+	ExpiringPoints int            `json:"expiring_points"` // within 30 days
+	NextExpiry     *time.Time     `json:"next_expiry,omitempty"`
+	History        []LoyaltyEntry `json:"history"`
+	Total          int64          `json:"total"`
+	Page           int            `json:"page"`
+	Limit          int            `json:"limit"`
+}
+
+type TierRecalculateResponse struct {
+	// Changed is the number of users that moved to another tier
+	Changed int64 `json:"changed"`
+}
+
+type StoreCreditResponse struct {
+	Balance float64            `json:"balance"`
+	History []StoreCreditEntry `json:"history"`
+	Total   int64              `json:"total"`
+	Page    int                `json:"page"`
+	Limit   int                `json:"limit"`
+}
+
+type GiftCardResponse struct {
+	GiftCard
+	Transactions []GiftCardTransaction `json:"transactions"`
+}
+
+type GiftCardListResponse struct {
+	Data  []GiftCard `json:"data"`
+	Total int64      `json:"total"`
+	Page  int        `json:"page"`
+	Limit int        `json:"limit"`
+}
+
+// GiftCardBalanceResponse is what a customer sees of a card: no ledger and
+// the code masked.
+type GiftCardBalanceResponse struct {
+	Code      string     `json:"code"`
+	Balance   float64    `json:"balance"`
+	Status    string     `json:"status"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type POSCustomerResponse struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Name        string    `json:"name"`
+	PhoneNumber string    `json:"phone_number"`
+	Tier        string    `json:"tier,omitempty"`
+	Points      int       `json:"points"`
+	StoreCredit float64   `json:"store_credit"`
+}
+
+type ReceiptLine struct {
+	Name      string  `json:"name"`
+	Modifiers string  `json:"modifiers,omitempty"`
+	Quantity  int     `json:"quantity"`
+	UnitPrice float64 `json:"unit_price"`
+	Amount    float64 `json:"amount"`
+}
+
+// ReceiptResponse is what the counter prints for a sale.
+type ReceiptResponse struct {
+	OrderID      string         `json:"order_id"`
+	Status       string         `json:"status"`
+	CreatedAt    time.Time      `json:"created_at"`
+	Cashier      string         `json:"cashier,omitempty"`
+	Customer     string         `json:"customer,omitempty"`
+	Lines        []ReceiptLine  `json:"lines"`
+	Total        float64        `json:"total"`
+	Payments     []OrderPayment `json:"payments"`
+	CashReceived float64        `json:"cash_received,omitempty"`
+	Change       float64        `json:"change"`
+	PointsEarned int            `json:"points_earned,omitempty"`
+}
+
+type TenderTotal struct {
+	Method string  `json:"method"`
+	Count  int64   `json:"count"`
+	Amount float64 `json:"amount"`
+}
+
+// SalesSummary sums up orders for a shift or Z-report. Voided and cancelled
+// orders only count under Voids.
+type SalesSummary struct {
+	Orders       int64         `json:"orders"`
+	GrossSales   float64       `json:"gross_sales"` // before discounts
+	Discounts    float64       `json:"discounts"`   // promotions, tiers and points
+	NetSales     float64       `json:"net_sales"`
+	Tenders      []TenderTotal `json:"tenders"`
+	Refunds      []TenderTotal `json:"refunds"`
+	Voids        int64         `json:"voids"`
+	VoidedAmount float64       `json:"voided_amount"`
+}
+
+type ShiftReport struct {
+	Shift     Shift          `json:"shift"`
+	Cashier   string         `json:"cashier"`
+	Sales     SalesSummary   `json:"sales"`
+	CashIn    float64        `json:"cash_in"`
+	CashOut   float64        `json:"cash_out"`
+	Movements []CashMovement `json:"movements"`
+	// ExpectedCash is the opening float plus cash sales and cash in, less
+	// cash out; final once the shift is closed
+	ExpectedCash float64 `json:"expected_cash"`
+}
+
+type ShiftListResponse struct {
+	Data  []Shift `json:"data"`
+	Total int64   `json:"total"`
+	Page  int     `json:"page"`
+	Limit int     `json:"limit"`
+}
+
+// ZReport is the end of day summary of every order placed that day and the
+// shifts opened that day.
+type ZReport struct {
+	Date       string        `json:"date"`
+	Sales      SalesSummary  `json:"sales"`
+	Shifts     []ShiftReport `json:"shifts"`
+	OpenShifts int           `json:"open_shifts"` // still to be closed
+	CashIn     float64       `json:"cash_in"`
+	CashOut    float64       `json:"cash_out"`
+	OverShort  float64       `json:"over_short"` // of the closed shifts
+}
@@ -0,0 +1,118 @@
+package models
+
+import "sort"
+
+// Roles stored in User.Role. Member is the customer default, every other
+// role is staff.
+const (
+	RoleAdmin   = "Admin"
+	RoleManager = "Manager"
+	RoleCashier = "Cashier"
+	RoleBaker   = "Baker"
+	RoleDriver  = "Driver"
+	RoleMember  = "Member"
+)
+
+// Permissions checked by middleware.RequirePermission.
+const (
+	PermProductsWrite      = "products.write"  // create, edit, delete, import and price changes
+	PermInventoryWrite     = "inventory.write" // stock levels only
+	PermPromotionsWrite    = "promotions.write"
+	PermOrdersRead         = "orders.read"
+	PermOrdersUpdateStatus = "orders.update_status"
+	PermReportsRead        = "reports.read"
+	PermAuditRead          = "audit.read"
+	PermStorageManage      = "storage.manage"
+	PermUsersRead          = "users.read"
+	PermUsersManage        = "users.manage"
+	PermLoyaltyManage      = "loyalty.manage"     // earn rules and balance adjustments
+	PermGiftCardsIssue     = "giftcards.issue"    // sell, activate and look up gift cards
+	PermStoredValueManage  = "storedvalue.manage" // void gift cards, issue and void store credit
+	PermPOSSell            = "pos.sell"           // ring up counter sales
+	PermPOSVoid            = "pos.void"           // void other cashiers' counter sales and close their shifts
+)
+
+// AllPermissions lists every permission, in display order.
+var AllPermissions = []string{
+	PermProductsWrite,
+	PermInventoryWrite,
+	PermPromotionsWrite,
+	PermOrdersRead,
+	PermOrdersUpdateStatus,
+	PermReportsRead,
+	PermAuditRead,
+	PermStorageManage,
+	PermUsersRead,
+	PermUsersManage,
+	PermLoyaltyManage,
+	PermGiftCardsIssue,
+	PermStoredValueManage,
+	PermPOSSell,
+	PermPOSVoid,
+}
+
+// RolePermissions maps each role to what it may do. Admin holds every permission.
+var RolePermissions = map[string][]string{
+	RoleAdmin: AllPermissions,
+	RoleManager: {
+		PermProductsWrite,
+		PermInventoryWrite,
+		PermPromotionsWrite,
+		PermOrdersRead,
+		PermOrdersUpdateStatus,
+		PermReportsRead,
+		PermAuditRead,
+		PermUsersRead,
+		PermLoyaltyManage,
+		PermGiftCardsIssue,
+		PermStoredValueManage,
+		PermPOSSell,
+		PermPOSVoid,
+	},
+	RoleCashier: {
+		PermOrdersRead,
+		PermOrdersUpdateStatus,
+		PermGiftCardsIssue,
+		PermPOSSell,
+	},
+	RoleBaker: {
+		PermInventoryWrite,
+		PermOrdersRead,
+	},
+	RoleDriver: {
+		PermOrdersRead,
+		PermOrdersUpdateStatus,
+	},
+	RoleMember: {},
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+// IsStaff reports whether role is a known role other than Member.
+func IsStaff(role string) bool {
+	return ValidRole(role) && role != RoleMember
+}
+
+// HasPermission reports whether role grants perm. Unknown roles grant nothing.
+func HasPermission(role, perm string) bool {
+	for _, p := range RolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Roles returns the role names sorted alphabetically.
+func Roles() []string {
+	roles := make([]string, 0, len(RolePermissions))
+	for role := range RolePermissions {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
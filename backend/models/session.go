@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is one logged in device. The refresh token is "<session id>.<secret>"
+// and only the SHA-256 of the secret is stored. Every refresh rotates the
+// secret; presenting the previous one again means it was copied, and the
+// session is revoked.
+type Session struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;index"`
+	TokenHash  string    `gorm:"type:char(64);not null"`
+	PrevHash   string    `gorm:"type:char(64)"`
+	UserAgent  string    `gorm:"type:text"`
+	IP         string    `gorm:"type:varchar(64)"`
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time `gorm:"not null;index"`
+	RevokedAt  *time.Time
+}
+
+// Active reports whether the session can still be refreshed.
+func (s *Session) Active() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
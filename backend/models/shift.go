@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Statuses of a Shift. A cashier has at most one open shift.
+const (
+	ShiftOpen   = "open"
+	ShiftClosed = "closed"
+)
+
+// Kinds of CashMovement.
+const (
+	CashIn  = "in"  // e.g. change brought to the drawer
+	CashOut = "out" // e.g. a bank drop or paying a supplier
+)
+
+// Shift is a cashier's session at the counter. Counter sales rung up while it
+// is open belong to it. Closing it records the counted cash against what the
+// drawer should hold.
+type Shift struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CashierID    uuid.UUID `json:"cashier_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_shifts_open,where:status = 'open'"`
+	Register     string    `json:"register,omitempty" gorm:"type:varchar(50)"`
+	Status       string    `json:"status" gorm:"type:varchar(10);not null;default:'open';check:status IN ('open','closed')"`
+	OpeningFloat float64   `json:"opening_float" gorm:"not null"`
+	OpenedAt     time.Time `json:"opened_at" gorm:"not null"`
+
+	// set on close
+	ClosedAt     *time.Time `json:"closed_at,omitempty"`
+	ClosedBy     *uuid.UUID `json:"closed_by,omitempty" gorm:"type:uuid"`
+	ExpectedCash *float64   `json:"expected_cash,omitempty"`
+	CountedCash  *float64   `json:"counted_cash,omitempty"`
+	OverShort    *float64   `json:"over_short,omitempty"` // counted less expected
+	CloseNote    string     `json:"close_note,omitempty" gorm:"type:text"`
+}
+
+// CashMovement is cash put into or taken out of the drawer outside a sale.
+type CashMovement struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	ShiftID   uint       `json:"shift_id" gorm:"not null;index"`
+	Kind      string     `json:"kind" gorm:"type:varchar(5);not null;check:kind IN ('in','out')"`
+	Amount    float64    `json:"amount" gorm:"not null"`
+	Reason    string     `json:"reason" gorm:"type:text;not null"`
+	ActorID   *uuid.UUID `json:"actor_id,omitempty" gorm:"type:uuid"`
+	CreatedAt time.Time  `json:"created_at"`
+}
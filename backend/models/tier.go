@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// MembershipTier is a level reached by spending at least MinSpend over the
+// last 12 months. Tiers are recalculated nightly.
+type MembershipTier struct {
+	ID              uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name            string    `json:"name" gorm:"type:varchar(50);not null;unique"`
+	MinSpend        float64   `json:"min_spend" gorm:"not null"`
+	DiscountPercent float64   `json:"discount_percent" gorm:"not null;default:0"`
+	FreeDelivery    bool      `json:"free_delivery" gorm:"not null;default:false"`
+	EarlyAccess     bool      `json:"early_access" gorm:"not null;default:false"` // may buy products before EarlyAccessUntil
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// DefaultTiers are created when there are none.
+var DefaultTiers = []MembershipTier{
+	{Name: "Silver", MinSpend: 3000, DiscountPercent: 2},
+	{Name: "Gold", MinSpend: 10000, DiscountPercent: 5, FreeDelivery: true},
+	{Name: "Platinum", MinSpend: 30000, DiscountPercent: 10, FreeDelivery: true, EarlyAccess: true},
+}
+
+// TierPricing is the member discount applied on top of promotions. With Stack
+// the tier discount is taken off the promotional price, otherwise the larger
+// of the two discounts wins.
+type TierPricing struct {
+	DiscountPercent float64
+	Stack           bool
+}
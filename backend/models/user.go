@@ -15,10 +15,24 @@ type User struct {
 	Role        string  `gorm:"default:Member"`
 	PhoneNumber *string `gorm:"size:10"`
 	Place       *string
-	Cart        *Cart `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	Cart        *Cart      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	DisabledAt  *time.Time // set while an admin has blocked the account
+	// TokenVersion is embedded in access tokens; bumping it invalidates all of them
+	TokenVersion int `gorm:"not null;default:0"`
+	// MFASecret is the AES-GCM sealed TOTP secret. It is set on enrollment and
+	// only enforced once MFAEnabledAt is set by a verified code.
+	MFASecret    string `gorm:"type:text"`
+	MFAEnabledAt *time.Time
+	MFALastStep  int64 `gorm:"not null;default:0"` // last TOTP step used, against replay
+	// TierID is the membership tier from the last recalculation, based on
+	// TierSpend, the spend over the 12 months before TierUpdatedAt
+	TierID        *uint
+	Tier          *MembershipTier `gorm:"foreignKey:TierID;constraint:OnDelete:SET NULL"`
+	TierSpend     float64         `gorm:"not null;default:0"`
+	TierUpdatedAt *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
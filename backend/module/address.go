@@ -0,0 +1,68 @@
+package module
+
+import (
+	"errors"
+	"strings"
+
+	"Bakery_Pos/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrAddressNotFound = errors.New("address not found")
+
+// NormalizeAddress trims d and checks the fields a delivery needs.
+func NormalizeAddress(d *models.AddressDetails) error {
+	for _, f := range []*string{&d.Label, &d.Recipient, &d.Phone, &d.Line1, &d.Line2, &d.District, &d.Province, &d.Postcode} {
+		*f = strings.TrimSpace(*f)
+	}
+	switch {
+	case d.Recipient == "":
+		return errors.New("recipient is required")
+	case !isDigits(d.Phone) || len(d.Phone) != 10:
+		return errors.New("phone must be 10 digits")
+	case d.Line1 == "":
+		return errors.New("address line 1 is required")
+	case d.Province == "":
+		return errors.New("province is required")
+	case !isDigits(d.Postcode) || len(d.Postcode) != 5:
+		return errors.New("postcode must be 5 digits")
+	case len(d.Label) > 50 || len(d.Recipient) > 100 || len(d.Line1) > 255 || len(d.Line2) > 255 || len(d.District) > 100 || len(d.Province) > 100:
+		return errors.New("address field is too long")
+	case (d.Lat == nil) != (d.Lng == nil):
+		return errors.New("lat and lng must be given together")
+	case d.Lat != nil && (*d.Lat < -90 || *d.Lat > 90 || *d.Lng < -180 || *d.Lng > 180):
+		return errors.New("lat or lng is out of range")
+	}
+	return nil
+}
+
+// MakeDefaultAddress clears the default flag of the user's other addresses.
+func MakeDefaultAddress(tx *gorm.DB, userID uuid.UUID, addressID uint) error {
+	return tx.Model(&models.Address{}).
+		Where("user_id = ? AND id <> ? AND is_default", userID, addressID).
+		Update("is_default", false).Error
+}
+
+// ShippingAddress picks the address to snapshot onto an order: addressID when
+// given, else the user's default. It is empty when the user has no address.
+func ShippingAddress(tx *gorm.DB, userID uuid.UUID, addressID *uint) (models.AddressDetails, error) {
+	var addr models.Address
+	query := tx.Where("user_id = ?", userID)
+	if addressID != nil {
+		query = query.Where("id = ?", *addressID)
+	} else {
+		query = query.Order("is_default DESC, updated_at DESC")
+	}
+	if err := query.First(&addr).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if addressID != nil {
+				return models.AddressDetails{}, ErrAddressNotFound
+			}
+			return models.AddressDetails{}, nil
+		}
+		return models.AddressDetails{}, err
+	}
+	return addr.AddressDetails, nil
+}
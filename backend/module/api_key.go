@@ -0,0 +1,159 @@
+package module
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"Bakery_Pos/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	apiKeyMarker = "bpk"
+	// DefaultAPIKeyRateLimit is the requests per minute of a key created without one.
+	DefaultAPIKeyRateLimit = 120
+	// apiKeyTouchInterval limits last_used_at writes to one per key per interval.
+	apiKeyTouchInterval = time.Minute
+)
+
+var ErrAPIKeyInvalid = errors.New("api key is invalid, expired or revoked")
+
+// GrantableScopes are the permissions an API key may hold: every permission
+// except managing users, so a leaked key cannot create accounts or more keys.
+func GrantableScopes() []string {
+	scopes := make([]string, 0, len(models.AllPermissions))
+	for _, perm := range models.AllPermissions {
+		if perm != models.PermUsersManage {
+			scopes = append(scopes, perm)
+		}
+	}
+	return scopes
+}
+
+// ValidateScopes checks that scopes is non empty and grantable.
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	grantable := GrantableScopes()
+	for _, scope := range scopes {
+		ok := false
+		for _, g := range grantable {
+			if scope == g {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Errorf("scope %q cannot be granted to an API key", scope)
+		}
+	}
+	return nil
+}
+
+// CreateAPIKey fills in the ID, prefix and hash of key, stores it and returns
+// the full key. It is not stored and cannot be shown again.
+func CreateAPIKey(tx *gorm.DB, key *models.APIKey) (string, error) {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	secret, err := newTokenSecret()
+	if err != nil {
+		return "", err
+	}
+
+	key.ID = uuid.New()
+	key.Prefix = hex.EncodeToString(buf)
+	key.KeyHash = hashToken(secret)
+	if key.RateLimit <= 0 {
+		key.RateLimit = DefaultAPIKeyRateLimit
+	}
+	if err := tx.Create(key).Error; err != nil {
+		return "", err
+	}
+	return apiKeyMarker + "_" + key.Prefix + "_" + secret, nil
+}
+
+// AuthenticateAPIKey returns the active key matching raw and records its use.
+func AuthenticateAPIKey(tx *gorm.DB, raw string) (models.APIKey, error) {
+	var key models.APIKey
+	marker, rest, _ := strings.Cut(raw, "_")
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if marker != apiKeyMarker || !ok || prefix == "" || secret == "" {
+		return key, ErrAPIKeyInvalid
+	}
+
+	if err := tx.Where("prefix = ?", prefix).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return key, ErrAPIKeyInvalid
+		}
+		return key, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(key.KeyHash)) != 1 || !key.Active() {
+		return key, ErrAPIKeyInvalid
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval {
+		if err := tx.Model(&key).Update("last_used_at", now).Error; err != nil {
+			return key, err
+		}
+	}
+	return key, nil
+}
+
+// RateLimiter counts requests per key in fixed one minute windows. It is in
+// memory, so with several instances each one enforces the limit on its own.
+type RateLimiter struct {
+	mu      sync.Mutex
+	windows map[string]rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{windows: make(map[string]rateWindow)}
+}
+
+// APIKeyLimiter enforces APIKey.RateLimit.
+var APIKeyLimiter = NewRateLimiter()
+
+// Allow counts a request for key and reports whether it is within limit per
+// minute, the requests left and when the window resets.
+func (l *RateLimiter) Allow(key string, limit int, now time.Time) (bool, int, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w := l.windows[key]
+	if now.Sub(w.start) >= time.Minute {
+		w = rateWindow{start: now}
+		// drop windows that ended, so revoked keys do not pile up
+		if len(l.windows) > 1000 {
+			for k, old := range l.windows {
+				if now.Sub(old.start) >= time.Minute {
+					delete(l.windows, k)
+				}
+			}
+		}
+	}
+	reset := w.start.Add(time.Minute)
+	if w.count >= limit {
+		l.windows[key] = w
+		return false, 0, reset
+	}
+	w.count++
+	l.windows[key] = w
+	return true, limit - w.count, reset
+}
@@ -0,0 +1,218 @@
+package module
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"Bakery_Pos/models"
+
+	"gorm.io/gorm"
+)
+
+// Attempts is the failure state of one login key.
+type Attempts struct {
+	Failures    int
+	LastFailure time.Time
+	LockedUntil *time.Time
+}
+
+// AttemptStore tracks failed logins. The memory store is enough for a single
+// instance; use the Postgres store when several instances share the load.
+type AttemptStore interface {
+	Get(ctx context.Context, key string) (Attempts, error)
+	// Fail records a failure. Failures older than window no longer count.
+	Fail(ctx context.Context, key string, window time.Duration) (Attempts, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+}
+
+// LoginPolicy decides when a key is slowed down and locked.
+type LoginPolicy struct {
+	MaxFailures  int           // failures within Window that lock the key
+	Window       time.Duration // failures older than this are forgotten
+	LockDuration time.Duration
+	DelayAfter   int           // failures before the delay starts
+	BaseDelay    time.Duration // doubled for every further failure
+	MaxDelay     time.Duration
+}
+
+var (
+	UserLoginPolicy = LoginPolicy{
+		MaxFailures:  5,
+		Window:       15 * time.Minute,
+		LockDuration: 15 * time.Minute,
+		DelayAfter:   2,
+		BaseDelay:    time.Second,
+		MaxDelay:     30 * time.Second,
+	}
+	// an IP may serve a whole shop behind NAT, so it gets more room
+	IPLoginPolicy = LoginPolicy{
+		MaxFailures:  30,
+		Window:       15 * time.Minute,
+		LockDuration: 15 * time.Minute,
+		DelayAfter:   10,
+		BaseDelay:    time.Second,
+		MaxDelay:     30 * time.Second,
+	}
+)
+
+func UserAttemptKey(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+func IPAttemptKey(ip string) string {
+	return "ip:" + ip
+}
+
+// CheckAttempt returns how long the caller has to wait before key may try
+// again, and whether that is because of a lockout. Zero means go ahead.
+func CheckAttempt(ctx context.Context, store AttemptStore, key string, policy LoginPolicy) (time.Duration, bool, error) {
+	state, err := store.Get(ctx, key)
+	if err != nil {
+		return 0, false, err
+	}
+	now := time.Now()
+	if state.LockedUntil != nil && now.Before(*state.LockedUntil) {
+		return state.LockedUntil.Sub(now), true, nil
+	}
+	if state.Failures == 0 || now.Sub(state.LastFailure) > policy.Window {
+		return 0, false, nil
+	}
+	if wait := state.LastFailure.Add(policy.delay(state.Failures)).Sub(now); wait > 0 {
+		return wait, false, nil
+	}
+	return 0, false, nil
+}
+
+// RecordFailure counts a failed login and locks the key once it reaches
+// MaxFailures. locked reports whether this failure caused the lock.
+func RecordFailure(ctx context.Context, store AttemptStore, key string, policy LoginPolicy) (state Attempts, locked bool, err error) {
+	state, err = store.Fail(ctx, key, policy.Window)
+	if err != nil {
+		return state, false, err
+	}
+	if state.Failures < policy.MaxFailures {
+		return state, false, nil
+	}
+	until := time.Now().Add(policy.LockDuration)
+	if err := store.Lock(ctx, key, until); err != nil {
+		return state, false, err
+	}
+	state.LockedUntil = &until
+	return state, state.Failures == policy.MaxFailures, nil
+}
+
+func (p LoginPolicy) delay(failures int) time.Duration {
+	if failures <= p.DelayAfter {
+		return 0
+	}
+	d := time.Duration(float64(p.BaseDelay) * math.Pow(2, float64(failures-p.DelayAfter-1)))
+	if d > p.MaxDelay || d <= 0 {
+		return p.MaxDelay
+	}
+	return d
+}
+
+// MemoryAttemptStore keeps attempts in process memory.
+type MemoryAttemptStore struct {
+	mu        sync.Mutex
+	entries   map[string]*Attempts
+	lastSweep time.Time
+}
+
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{entries: make(map[string]*Attempts), lastSweep: time.Now()}
+}
+
+func (s *MemoryAttemptStore) Get(_ context.Context, key string) (Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok {
+		return *e, nil
+	}
+	return Attempts{}, nil
+}
+
+func (s *MemoryAttemptStore) Fail(_ context.Context, key string, window time.Duration) (Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+	e, ok := s.entries[key]
+	if !ok {
+		e = &Attempts{}
+		s.entries[key] = e
+	}
+	if now.Sub(e.LastFailure) > window {
+		e.Failures = 0
+	}
+	e.Failures++
+	e.LastFailure = now
+	return *e, nil
+}
+
+func (s *MemoryAttemptStore) Lock(_ context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok {
+		e.LockedUntil = &until
+	}
+	return nil
+}
+
+func (s *MemoryAttemptStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// sweep drops entries idle for a day, at most once an hour.
+func (s *MemoryAttemptStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Hour {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if now.Sub(e.LastFailure) > 24*time.Hour && (e.LockedUntil == nil || now.After(*e.LockedUntil)) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// PostgresAttemptStore keeps attempts in the login_attempts table so every
+// instance sees the same counters.
+type PostgresAttemptStore struct {
+	DB *gorm.DB
+}
+
+func (s PostgresAttemptStore) Get(ctx context.Context, key string) (Attempts, error) {
+	var row models.LoginAttempt
+	err := s.DB.WithContext(ctx).Where("key = ?", key).Limit(1).Find(&row).Error
+	return Attempts{Failures: row.Failures, LastFailure: row.LastFailureAt, LockedUntil: row.LockedUntil}, err
+}
+
+func (s PostgresAttemptStore) Fail(ctx context.Context, key string, window time.Duration) (Attempts, error) {
+	now := time.Now()
+	var row models.LoginAttempt
+	err := s.DB.WithContext(ctx).Raw(`
+		INSERT INTO login_attempts (key, failures, last_failure_at) VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING key, failures, last_failure_at, locked_until`,
+		key, now, now.Add(-window)).Scan(&row).Error
+	return Attempts{Failures: row.Failures, LastFailure: row.LastFailureAt, LockedUntil: row.LockedUntil}, err
+}
+
+func (s PostgresAttemptStore) Lock(ctx context.Context, key string, until time.Time) error {
+	return s.DB.WithContext(ctx).Model(&models.LoginAttempt{}).Where("key = ?", key).Update("locked_until", until).Error
+}
+
+func (s PostgresAttemptStore) Reset(ctx context.Context, key string) error {
+	return s.DB.WithContext(ctx).Where("key = ?", key).Delete(&models.LoginAttempt{}).Error
+}
@@ -0,0 +1,44 @@
+package module
+
+import (
+	"Bakery_Pos/models"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NewAuditLog builds an audit entry, encoding before/after as JSON.
+// actorID may be empty for system initiated changes.
+func NewAuditLog(actorID, action, entityType, entityID string, before, after any) models.AuditLog {
+	entry := models.AuditLog{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Before:     encodeAuditValue(before),
+		After:      encodeAuditValue(after),
+	}
+	if id, err := uuid.Parse(actorID); err == nil {
+		entry.ActorID = &id
+	}
+	return entry
+}
+
+// RecordAudit saves audit entries using tx so they commit or roll back with the change itself.
+func RecordAudit(tx *gorm.DB, entries ...models.AuditLog) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return tx.Create(&entries).Error
+}
+
+func encodeAuditValue(v any) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,94 @@
+package module
+
+import (
+	"errors"
+	"strconv"
+)
+
+var ErrInvalidBarcode = errors.New("barcode must be a 12 digit UPC-A or 13 digit EAN-13 with a valid check digit")
+
+// Kinds of value embedded in an in-store (prefix 20-29) barcode.
+const (
+	InStoreWeight = "weight" // value is grams, prefixes 20-24
+	InStorePrice  = "price"  // value is satang, prefixes 25-29
+)
+
+// InStoreBarcode is a decoded variable measure barcode: 2 + type digit, 5 digit PLU,
+// 5 digit value and the EAN-13 check digit.
+type InStoreBarcode struct {
+	PLU   string
+	Kind  string
+	Value int
+}
+
+// NormalizeGTIN validates an EAN-13 or UPC-A code and returns it as 13 digits
+// (UPC-A gets a leading zero) so both spellings of the same item match.
+func NormalizeGTIN(code string) (string, error) {
+	if !isDigits(code) {
+		return "", ErrInvalidBarcode
+	}
+	switch len(code) {
+	case 12:
+		code = "0" + code
+	case 13:
+	default:
+		return "", ErrInvalidBarcode
+	}
+	if gtinCheckDigit(code[:12]) != code[12] {
+		return "", ErrInvalidBarcode
+	}
+	return code, nil
+}
+
+// IsInStoreBarcode reports whether a normalized GTIN is in the 20-29 range
+// reserved for in-store use.
+func IsInStoreBarcode(gtin string) bool {
+	return len(gtin) == 13 && gtin[0] == '2'
+}
+
+// DecodeInStoreBarcode splits a normalized in-store GTIN into its PLU and embedded value.
+func DecodeInStoreBarcode(gtin string) (InStoreBarcode, bool) {
+	if !IsInStoreBarcode(gtin) {
+		return InStoreBarcode{}, false
+	}
+	value, err := strconv.Atoi(gtin[7:12])
+	if err != nil {
+		return InStoreBarcode{}, false
+	}
+	kind := InStoreWeight
+	if gtin[1] >= '5' {
+		kind = InStorePrice
+	}
+	return InStoreBarcode{PLU: gtin[2:7], Kind: kind, Value: value}, true
+}
+
+// ValidPLU reports whether s is a 5 digit in-store item code.
+func ValidPLU(s string) bool {
+	return len(s) == 5 && isDigits(s)
+}
+
+// gtinCheckDigit computes the mod 10 check digit, weighting digits 3,1,3,...
+// from the right.
+func gtinCheckDigit(digits string) byte {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
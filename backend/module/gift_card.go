@@ -0,0 +1,283 @@
+package module
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"Bakery_Pos/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GiftCardCodeLength is the number of digits of a gift card code, the last
+// one being the Luhn check digit.
+const GiftCardCodeLength = 16
+
+var (
+	ErrGiftCardCode     = errors.New("gift card code is not valid")
+	ErrGiftCardNotFound = errors.New("gift card not found")
+	ErrGiftCardInactive = errors.New("gift card has not been activated")
+	ErrGiftCardVoid     = errors.New("gift card has been voided")
+	ErrGiftCardExpired  = errors.New("gift card has expired")
+	ErrGiftCardEmpty    = errors.New("gift card has no balance left")
+	ErrGiftCardState    = errors.New("gift card is not in a state that allows this")
+)
+
+// NormalizeGiftCardCode strips spaces and dashes from a typed or scanned code
+// and checks its length and check digit.
+func NormalizeGiftCardCode(code string) (string, error) {
+	code = strings.NewReplacer(" ", "", "-", "").Replace(code)
+	if len(code) != GiftCardCodeLength || !isDigits(code) {
+		return "", ErrGiftCardCode
+	}
+	if luhnCheckDigit(code[:len(code)-1]) != code[len(code)-1] {
+		return "", ErrGiftCardCode
+	}
+	return code, nil
+}
+
+// MaskGiftCardCode keeps the last four digits, for receipts and customers.
+func MaskGiftCardCode(code string) string {
+	if len(code) <= 4 {
+		return code
+	}
+	return "**** " + code[len(code)-4:]
+}
+
+func newGiftCardCode() (string, error) {
+	out := make([]byte, 0, GiftCardCodeLength)
+	for i := 0; i < GiftCardCodeLength-1; i++ {
+		// no leading zero, so the code survives spreadsheets
+		digits, first := int64(10), int64(0)
+		if i == 0 {
+			digits, first = 9, 1
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(digits))
+		if err != nil {
+			return "", err
+		}
+		out = append(out, byte('0'+first+n.Int64()))
+	}
+	return string(append(out, luhnCheckDigit(string(out)))), nil
+}
+
+// luhnCheckDigit computes the mod 10 check digit, doubling every second digit
+// from the right of the payload.
+func luhnCheckDigit(payload string) byte {
+	sum := 0
+	for i := len(payload) - 1; i >= 0; i-- {
+		d := int(payload[i] - '0')
+		if (len(payload)-1-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// IssueGiftCard creates card with a new code and its InitialValue as the
+// balance. The caller sets the value, expiry and whether it starts active.
+func IssueGiftCard(tx *gorm.DB, card *models.GiftCard, actorID *uuid.UUID) error {
+	card.InitialValue = roundBaht(card.InitialValue)
+	card.Balance = card.InitialValue
+	card.IssuedBy = actorID
+	if card.Status == models.GiftCardActive {
+		now := time.Now()
+		card.ActivatedAt = &now
+	} else {
+		card.Status = models.GiftCardInactive
+	}
+
+	for attempt := 0; ; attempt++ {
+		code, err := newGiftCardCode()
+		if err != nil {
+			return err
+		}
+		var taken int64
+		if err := tx.Model(&models.GiftCard{}).Where("code = ?", code).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken == 0 {
+			card.Code = code
+			break
+		}
+		if attempt == 4 {
+			return errors.New("could not generate a unique gift card code")
+		}
+	}
+	if err := tx.Create(card).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.GiftCardTransaction{
+		GiftCardID:   card.ID,
+		Kind:         models.StoredValueIssue,
+		Amount:       card.Balance,
+		BalanceAfter: card.Balance,
+		ActorID:      actorID,
+	}).Error
+}
+
+// ActivateGiftCard makes an inactive card usable.
+func ActivateGiftCard(tx *gorm.DB, id uint) (models.GiftCard, error) {
+	card, err := lockGiftCard(tx, "id = ?", id)
+	if err != nil {
+		return card, err
+	}
+	if card.Status != models.GiftCardInactive {
+		return card, ErrGiftCardState
+	}
+	now := time.Now()
+	card.Status = models.GiftCardActive
+	card.ActivatedAt = &now
+	return card, tx.Save(&card).Error
+}
+
+// VoidGiftCard writes off the remaining balance and blocks the card for good.
+func VoidGiftCard(tx *gorm.DB, id uint, reason string, actorID *uuid.UUID) (models.GiftCard, error) {
+	card, err := lockGiftCard(tx, "id = ?", id)
+	if err != nil {
+		return card, err
+	}
+	if card.Status == models.GiftCardVoid {
+		return card, ErrGiftCardState
+	}
+	if err := tx.Create(&models.GiftCardTransaction{
+		GiftCardID: card.ID,
+		Kind:       models.StoredValueVoid,
+		Amount:     -card.Balance,
+		Note:       reason,
+		ActorID:    actorID,
+	}).Error; err != nil {
+		return card, err
+	}
+	now := time.Now()
+	card.Status = models.GiftCardVoid
+	card.VoidedAt = &now
+	card.Balance = 0
+	return card, tx.Save(&card).Error
+}
+
+// RedeemGiftCard pays up to limit of order with the card and returns the
+// payment; a card with less balance than limit pays what it has.
+func RedeemGiftCard(tx *gorm.DB, order *models.Order, code string, limit float64) (models.OrderPayment, error) {
+	payment := models.OrderPayment{
+		OrderID:   order.ID,
+		Method:    models.TenderGiftCard,
+		Reference: MaskGiftCardCode(code),
+	}
+	card, err := lockGiftCard(tx, "code = ?", code)
+	if err != nil {
+		return payment, err
+	}
+	switch {
+	case card.Status == models.GiftCardInactive:
+		return payment, ErrGiftCardInactive
+	case card.Status == models.GiftCardVoid:
+		return payment, ErrGiftCardVoid
+	case card.Expired(time.Now()):
+		return payment, ErrGiftCardExpired
+	case card.Balance <= 0:
+		return payment, ErrGiftCardEmpty
+	}
+
+	payment.GiftCardID = &card.ID
+	payment.Amount = roundBaht(min(card.Balance, limit))
+	card.Balance = roundBaht(card.Balance - payment.Amount)
+	if err := tx.Model(&card).Update("balance", card.Balance).Error; err != nil {
+		return payment, err
+	}
+	if err := tx.Create(&models.GiftCardTransaction{
+		GiftCardID:   card.ID,
+		OrderID:      &order.ID,
+		Kind:         models.StoredValueRedeem,
+		Amount:       -payment.Amount,
+		BalanceAfter: card.Balance,
+	}).Error; err != nil {
+		return payment, err
+	}
+	return payment, tx.Create(&payment).Error
+}
+
+// RefundOrderPayments gives the tenders of a cancelled order back. Gift card
+// payments go back onto the card, or to the customer's store credit when the
+// card has since expired or been voided and the order has a customer.
+// Running it twice does nothing more.
+func RefundOrderPayments(tx *gorm.DB, order *models.Order) error {
+	var payments []models.OrderPayment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND refunded_at IS NULL", order.ID).Order("id").Find(&payments).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, p := range payments {
+		hasCustomer := order.UserID != nil
+		toCredit := p.Method == models.TenderStoreCredit
+		if p.Method == models.TenderGiftCard && p.GiftCardID != nil {
+			card, err := lockGiftCard(tx, "id = ?", *p.GiftCardID)
+			if err != nil {
+				return err
+			}
+			usable := card.Status == models.GiftCardActive && !card.Expired(now)
+			if usable || !hasCustomer {
+				card.Balance = roundBaht(card.Balance + p.Amount)
+				if err := tx.Model(&card).Update("balance", card.Balance).Error; err != nil {
+					return err
+				}
+				if err := tx.Create(&models.GiftCardTransaction{
+					GiftCardID:   card.ID,
+					OrderID:      &order.ID,
+					Kind:         models.StoredValueRefund,
+					Amount:       p.Amount,
+					BalanceAfter: card.Balance,
+				}).Error; err != nil {
+					return err
+				}
+			} else {
+				toCredit = true
+			}
+		}
+		if toCredit && hasCustomer {
+			if err := postStoreCredit(tx, &models.StoreCreditEntry{
+				UserID:  *order.UserID,
+				OrderID: &order.ID,
+				Kind:    models.StoredValueRefund,
+				Amount:  p.Amount,
+				Reason:  "order cancelled",
+			}); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&p).Update("refunded_at", now).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindGiftCard looks a card up by its normalized code.
+func FindGiftCard(tx *gorm.DB, code string) (models.GiftCard, error) {
+	var card models.GiftCard
+	err := tx.Where("code = ?", code).First(&card).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = ErrGiftCardNotFound
+	}
+	return card, err
+}
+
+func lockGiftCard(tx *gorm.DB, query string, arg any) (models.GiftCard, error) {
+	var card models.GiftCard
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(query, arg).First(&card).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = ErrGiftCardNotFound
+	}
+	return card, err
+}
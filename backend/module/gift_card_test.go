@@ -0,0 +1,50 @@
+package module
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeGiftCardCode(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+		err  error
+	}{
+		{"valid", "4111111111111111", "4111111111111111", nil},
+		{"valid with other digits", "5555555555554444", "5555555555554444", nil},
+		{"dashes are ignored", "4111-1111-1111-1111", "4111111111111111", nil},
+		{"spaces are ignored", "4111 1111 1111 1111", "4111111111111111", nil},
+		{"wrong check digit", "4111111111111112", "", ErrGiftCardCode},
+		{"single digit typo", "4111111111121111", "", ErrGiftCardCode},
+		{"adjacent digits swapped", "5555555555545444", "", ErrGiftCardCode},
+		{"too short", "411111111111111", "", ErrGiftCardCode},
+		{"too long", "41111111111111111", "", ErrGiftCardCode},
+		{"not digits", "4111a11111111111", "", ErrGiftCardCode},
+		{"empty", "", "", ErrGiftCardCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeGiftCardCode(tt.code)
+			if got != tt.want || !errors.Is(err, tt.err) {
+				t.Errorf("NormalizeGiftCardCode(%q) = %q, %v; want %q, %v", tt.code, got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestNewGiftCardCodeIsValid(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := newGiftCardCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NormalizeGiftCardCode(code); err != nil {
+			t.Fatalf("newGiftCardCode() = %q, which NormalizeGiftCardCode rejects", code)
+		}
+		if code[0] == '0' {
+			t.Fatalf("newGiftCardCode() = %q starts with 0", code)
+		}
+	}
+}
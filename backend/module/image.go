@@ -0,0 +1,126 @@
+package module
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// ProductImageBucket is the public bucket holding product images and renditions.
+const ProductImageBucket = "product-images"
+
+// MaxImageSize is the largest product image accepted on confirm.
+const MaxImageSize = 5 << 20
+
+// MaxImagePixels caps width times height. A small PNG or GIF can declare huge
+// dimensions, and decoding allocates for all of them.
+const MaxImagePixels = 40_000_000
+
+// Rendition widths generated for every confirmed product image.
+const (
+	ThumbnailSize = 200
+	MediumSize    = 800
+)
+
+// ImageExtensions maps the accepted upload content types to file extensions.
+var ImageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/webp": ".webp",
+}
+
+// ImageRenditions holds the encoded JPEG renditions of an uploaded image.
+type ImageRenditions struct {
+	ContentType string
+	Width       int
+	Height      int
+	Thumbnail   []byte
+	Medium      []byte
+}
+
+// ProcessImage checks the size, dimensions and sniffed content type of an uploaded image and
+// renders thumbnail and medium JPEGs that fit inside a square of the given size.
+func ProcessImage(data []byte) (*ImageRenditions, error) {
+	if len(data) == 0 {
+		return nil, errors.New("image is empty")
+	}
+	if len(data) > MaxImageSize {
+		return nil, fmt.Errorf("image is larger than %d MB", MaxImageSize>>20)
+	}
+
+	contentType := http.DetectContentType(data)
+	if _, ok := ImageExtensions[contentType]; !ok {
+		return nil, fmt.Errorf("unsupported image type %s", contentType)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxImagePixels {
+		return nil, fmt.Errorf("image is larger than %d megapixels", MaxImagePixels/1_000_000)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	thumb, err := encodeRendition(src, ThumbnailSize)
+	if err != nil {
+		return nil, err
+	}
+	medium, err := encodeRendition(src, MediumSize)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	return &ImageRenditions{
+		ContentType: contentType,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		Thumbnail:   thumb,
+		Medium:      medium,
+	}, nil
+}
+
+func encodeRendition(src image.Image, size int) ([]byte, error) {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			h = h * size / w
+			w = size
+		} else {
+			w = w * size / h
+			h = size
+		}
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+
+	// JPEG has no alpha channel, so flatten transparent images onto white
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, fmt.Errorf("failed to encode rendition: %w", err)
+	}
+	return buf.Bytes(), nil
+}
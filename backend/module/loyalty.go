@@ -0,0 +1,356 @@
+package module
+
+import (
+	"errors"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"Bakery_Pos/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInsufficientPoints = errors.New("not enough points")
+
+// LoyaltySettings configure the points program. They come from the
+// environment so the shop can tune them without a release.
+type LoyaltySettings struct {
+	PointsPerBaht float64       // LOYALTY_POINTS_PER_BAHT, default 0.1 (1 point per 10 baht)
+	PointValue    float64       // LOYALTY_POINT_VALUE, baht per redeemed point, default 0.25
+	RedeemCap     float64       // LOYALTY_REDEEM_CAP, share of the subtotal points may pay, default 0.5
+	EarnStatus    string        // LOYALTY_EARN_STATUS, confirmed or delivered (default)
+	Expiry        time.Duration // LOYALTY_EXPIRY_DAYS, default 365
+}
+
+func LoyaltyConfig() LoyaltySettings {
+	s := LoyaltySettings{
+		PointsPerBaht: envFloat("LOYALTY_POINTS_PER_BAHT", 0.1),
+		PointValue:    envFloat("LOYALTY_POINT_VALUE", 0.25),
+		RedeemCap:     envFloat("LOYALTY_REDEEM_CAP", 0.5),
+		EarnStatus:    "delivered",
+		Expiry:        time.Duration(envFloat("LOYALTY_EXPIRY_DAYS", 365)*24) * time.Hour,
+	}
+	if os.Getenv("LOYALTY_EARN_STATUS") == "confirmed" {
+		s.EarnStatus = "confirmed"
+	}
+	return s
+}
+
+func envFloat(key string, def float64) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil || v < 0 {
+		return def
+	}
+	return v
+}
+
+// LoyaltyBalance returns the user's current points. Credits that expired
+// but have not been written off by ExpirePoints yet already do not count.
+func LoyaltyBalance(tx *gorm.DB, userID uuid.UUID) (int, error) {
+	var balance int
+	err := tx.Model(&models.LoyaltyEntry{}).Where("user_id = ?", userID).
+		Select("COALESCE(SUM(points), 0) - COALESCE(SUM(CASE WHEN expires_at <= ? THEN remaining ELSE 0 END), 0)", time.Now()).
+		Scan(&balance).Error
+	return balance, err
+}
+
+// MaxRedeemable is how many of balance points may be spent on an order with
+// subtotal under the redeem cap.
+func MaxRedeemable(cfg LoyaltySettings, subtotal float64, balance int) int {
+	if cfg.PointValue <= 0 {
+		return 0
+	}
+	limit := int(math.Floor(subtotal * cfg.RedeemCap / cfg.PointValue))
+	if balance < limit {
+		limit = balance
+	}
+	if limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// RedeemPoints spends points on order. The caller checks the cap and sets
+// the discount on the order; this checks the balance under a lock.
+func RedeemPoints(tx *gorm.DB, order *models.Order, points int) error {
+	if order.UserID == nil {
+		return ErrInsufficientPoints
+	}
+	userID := *order.UserID
+	if err := lockUser(tx, userID); err != nil {
+		return err
+	}
+	balance, err := LoyaltyBalance(tx, userID)
+	if err != nil {
+		return err
+	}
+	if points > balance {
+		return ErrInsufficientPoints
+	}
+	return debitPoints(tx, &models.LoyaltyEntry{
+		UserID:  userID,
+		OrderID: &order.ID,
+		Kind:    models.LoyaltyRedeem,
+		Points:  -points,
+	})
+}
+
+// EarnPoints credits the points for order once; later calls and orders
+// without a customer do nothing. Each
+// item earns on what was paid for it after the points discount, times the
+// largest matching rule multiplier.
+func EarnPoints(tx *gorm.DB, order *models.Order) (int, error) {
+	if order.UserID == nil {
+		return 0, nil
+	}
+	userID := *order.UserID
+	if err := lockUser(tx, userID); err != nil {
+		return 0, err
+	}
+	var earned int64
+	if err := tx.Model(&models.LoyaltyEntry{}).Where("order_id = ? AND kind = ?", order.ID, models.LoyaltyEarn).Count(&earned).Error; err != nil {
+		return 0, err
+	}
+	if earned > 0 {
+		return 0, nil
+	}
+
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ?", order.ID).Find(&items).Error; err != nil {
+		return 0, err
+	}
+	var rules []models.LoyaltyRule
+	if err := tx.Where("is_active").Find(&rules).Error; err != nil {
+		return 0, err
+	}
+
+	cfg := LoyaltyConfig()
+	now := time.Now()
+	var subtotal, weighted float64
+	for _, item := range items {
+		line := item.Price * float64(item.Quantity)
+		multiplier := 1.0
+		for i := range rules {
+			if rules[i].Applies(item.Tag, now) && rules[i].Multiplier > multiplier {
+				multiplier = rules[i].Multiplier
+			}
+		}
+		subtotal += line
+		weighted += line * multiplier
+	}
+	if subtotal <= 0 {
+		return 0, nil
+	}
+	paid := (order.Total - order.DeliveryFee) / subtotal
+	if paid > 1 {
+		paid = 1
+	}
+	points := int(math.Floor(weighted * paid * cfg.PointsPerBaht))
+	if points <= 0 {
+		return 0, nil
+	}
+
+	return points, creditPoints(tx, &models.LoyaltyEntry{
+		UserID:  userID,
+		OrderID: &order.ID,
+		Kind:    models.LoyaltyEarn,
+		Points:  points,
+	}, cfg.Expiry)
+}
+
+// ReverseOrderPoints undoes the points of a cancelled order: earned points are
+// taken back, even into a negative balance, and redeemed points are refunded.
+// Running it twice does nothing more.
+func ReverseOrderPoints(tx *gorm.DB, order *models.Order) error {
+	if order.UserID == nil {
+		return nil
+	}
+	userID := *order.UserID
+	if err := lockUser(tx, userID); err != nil {
+		return err
+	}
+	var entries []models.LoyaltyEntry
+	if err := tx.Where("order_id = ?", order.ID).Find(&entries).Error; err != nil {
+		return err
+	}
+	var earned, reversed, redeemed, refunded int
+	for _, e := range entries {
+		switch e.Kind {
+		case models.LoyaltyEarn:
+			earned += e.Points
+		case models.LoyaltyReverse:
+			reversed -= e.Points
+		case models.LoyaltyRedeem:
+			redeemed -= e.Points
+		case models.LoyaltyRefund:
+			refunded += e.Points
+		}
+	}
+
+	if n := earned - reversed; n > 0 {
+		if err := debitPoints(tx, &models.LoyaltyEntry{
+			UserID:  userID,
+			OrderID: &order.ID,
+			Kind:    models.LoyaltyReverse,
+			Points:  -n,
+			Reason:  "order cancelled",
+		}); err != nil {
+			return err
+		}
+	}
+	if n := redeemed - refunded; n > 0 {
+		if err := creditPoints(tx, &models.LoyaltyEntry{
+			UserID:  userID,
+			OrderID: &order.ID,
+			Kind:    models.LoyaltyRefund,
+			Points:  n,
+			Reason:  "order cancelled",
+		}, LoyaltyConfig().Expiry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AdjustPoints adds or, with negative points, removes points by hand. Removal
+// cannot take the balance below zero.
+func AdjustPoints(tx *gorm.DB, userID uuid.UUID, points int, reason string, actorID *uuid.UUID) (models.LoyaltyEntry, error) {
+	entry := models.LoyaltyEntry{
+		UserID:  userID,
+		Kind:    models.LoyaltyAdjust,
+		Points:  points,
+		Reason:  reason,
+		ActorID: actorID,
+	}
+	if err := lockUser(tx, userID); err != nil {
+		return entry, err
+	}
+	if points > 0 {
+		err := creditPoints(tx, &entry, LoyaltyConfig().Expiry)
+		return entry, err
+	}
+	balance, err := LoyaltyBalance(tx, userID)
+	if err != nil {
+		return entry, err
+	}
+	if -points > balance {
+		return entry, ErrInsufficientPoints
+	}
+	err = debitPoints(tx, &entry)
+	return entry, err
+}
+
+// ExpirePoints writes off what is left of credits that expired before now and
+// returns the number of points expired.
+func ExpirePoints(tx *gorm.DB, now time.Time) (int, error) {
+	var credits []models.LoyaltyEntry
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("remaining > 0 AND expires_at <= ?", now).
+		Order("user_id, id").Find(&credits).Error; err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, credit := range credits {
+		if err := tx.Model(&credit).Update("remaining", 0).Error; err != nil {
+			return total, err
+		}
+		expired := models.LoyaltyEntry{
+			UserID: credit.UserID,
+			Kind:   models.LoyaltyExpire,
+			Points: -credit.Remaining,
+			Reason: "points from " + credit.CreatedAt.Format("2006-01-02") + " expired",
+		}
+		if err := tx.Create(&expired).Error; err != nil {
+			return total, err
+		}
+		total += credit.Remaining
+	}
+	return total, nil
+}
+
+// lockUser serialises ledger changes of one user, points or store credit, on
+// their user row.
+func lockUser(tx *gorm.DB, userID uuid.UUID) error {
+	var user models.User
+	return tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").Where("id = ?", userID).First(&user).Error
+}
+
+func creditPoints(tx *gorm.DB, entry *models.LoyaltyEntry, expiry time.Duration) error {
+	entry.Remaining = entry.Points
+	if expiry > 0 {
+		expiresAt := time.Now().Add(expiry)
+		entry.ExpiresAt = &expiresAt
+	}
+	return tx.Create(entry).Error
+}
+
+// debitPoints records a negative entry and uses up the oldest unexpired
+// credits by the same amount, as far as they go.
+func debitPoints(tx *gorm.DB, entry *models.LoyaltyEntry) error {
+	if err := tx.Create(entry).Error; err != nil {
+		return err
+	}
+
+	var credits []models.LoyaltyEntry
+	if err := tx.Where("user_id = ? AND remaining > 0 AND (expires_at IS NULL OR expires_at > ?)", entry.UserID, time.Now()).
+		Order("expires_at ASC NULLS LAST, id ASC").Find(&credits).Error; err != nil {
+		return err
+	}
+	left := -entry.Points
+	for _, credit := range credits {
+		if left <= 0 {
+			break
+		}
+		use := min(credit.Remaining, left)
+		if err := tx.Model(&credit).Update("remaining", credit.Remaining-use).Error; err != nil {
+			return err
+		}
+		left -= use
+	}
+	return nil
+}
+
+// LoyaltySummary returns the user's balance, the points expiring within 30
+// days and a page of the ledger, newest first.
+func LoyaltySummary(tx *gorm.DB, userID uuid.UUID, page, limit int) (models.LoyaltyResponse, error) {
+	cfg := LoyaltyConfig()
+	resp := models.LoyaltyResponse{
+		PointValue: cfg.PointValue,
+		RedeemCap:  cfg.RedeemCap,
+		History:    []models.LoyaltyEntry{},
+		Page:       page,
+		Limit:      limit,
+	}
+
+	var err error
+	if resp.Balance, err = LoyaltyBalance(tx, userID); err != nil {
+		return resp, err
+	}
+
+	now := time.Now()
+	var expiring struct {
+		Points int
+		Next   *time.Time
+	}
+	if err := tx.Model(&models.LoyaltyEntry{}).
+		Select("COALESCE(SUM(CASE WHEN expires_at <= ? THEN remaining ELSE 0 END), 0) AS points, MIN(expires_at) AS next", now.Add(30*24*time.Hour)).
+		Where("user_id = ? AND remaining > 0 AND expires_at > ?", userID, now).
+		Scan(&expiring).Error; err != nil {
+		return resp, err
+	}
+	resp.ExpiringPoints = expiring.Points
+	resp.NextExpiry = expiring.Next
+
+	history := tx.Model(&models.LoyaltyEntry{}).Where("user_id = ?", userID)
+	if err := history.Count(&resp.Total).Error; err != nil {
+		return resp, err
+	}
+	err = history.Order("created_at DESC, id DESC").Limit(limit).Offset((page - 1) * limit).Find(&resp.History).Error
+	return resp, err
+}
@@ -0,0 +1,234 @@
+package module
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"Bakery_Pos/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// MFATokenTTL is how long the second login step may take.
+	MFATokenTTL     = 5 * time.Minute
+	BackupCodeCount = 10
+	mfaIssuer       = "Sweet Heaven"
+)
+
+var ErrMFAInvalid = errors.New("invalid two-factor code")
+
+// MFARequired reports whether role must have 2FA enabled before using staff
+// permissions, which is the case for every staff role when
+// MFA_REQUIRED_FOR_STAFF=true.
+func MFARequired(role string) bool {
+	return os.Getenv("MFA_REQUIRED_FOR_STAFF") == "true" && models.IsStaff(role)
+}
+
+// MFAIssuer is the name shown in authenticator apps, from MFA_ISSUER.
+func MFAIssuer() string {
+	if v := os.Getenv("MFA_ISSUER"); v != "" {
+		return v
+	}
+	return mfaIssuer
+}
+
+// SealMFASecret encrypts a TOTP secret with AES-GCM so a database dump alone
+// does not reveal it. The key is MFA_SECRET_KEY, or JWT_SECRET when unset.
+func SealMFASecret(secret string) (string, error) {
+	gcm, err := mfaCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func OpenMFASecret(sealed string) (string, error) {
+	gcm, err := mfaCipher()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < gcm.NonceSize() {
+		return "", errors.New("corrupt MFA secret")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func mfaCipher() (cipher.AEAD, error) {
+	key := os.Getenv("MFA_SECRET_KEY")
+	if key == "" {
+		key = os.Getenv("JWT_SECRET")
+	}
+	if key == "" {
+		return nil, errors.New("MFA_SECRET_KEY not set")
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ReplaceBackupCodes deletes the user's backup codes and creates a new set,
+// returned in plain text for the user to write down.
+func ReplaceBackupCodes(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.MFABackupCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, BackupCodeCount)
+	rows := make([]models.MFABackupCode, 0, BackupCodeCount)
+	for i := 0; i < BackupCodeCount; i++ {
+		code, err := newBackupCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		rows = append(rows, models.MFABackupCode{UserID: userID, CodeHash: hashToken(normalizeBackupCode(code))})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifyMFA accepts a current TOTP code or an unused backup code. A TOTP step
+// or backup code that succeeds is consumed.
+func VerifyMFA(tx *gorm.DB, user *models.User, code string) error {
+	if err := VerifyTOTP(tx, user, code); !errors.Is(err, ErrMFAInvalid) {
+		return err
+	}
+
+	res := tx.Model(&models.MFABackupCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashToken(normalizeBackupCode(code))).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrMFAInvalid
+	}
+	return nil
+}
+
+// VerifyTOTP is VerifyMFA without the backup code fallback, for actions that
+// must prove the authenticator itself is still at hand.
+func VerifyTOTP(tx *gorm.DB, user *models.User, code string) error {
+	if user.MFASecret == "" {
+		return ErrMFAInvalid
+	}
+	secret, err := OpenMFASecret(user.MFASecret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := ValidateTOTP(secret, code, time.Now(), user.MFALastStep)
+	if !ok {
+		return ErrMFAInvalid
+	}
+	// guarded so the same code cannot log in twice concurrently
+	res := tx.Model(&models.User{}).Where("id = ? AND mfa_last_step < ?", user.ID, step).Update("mfa_last_step", step)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrMFAInvalid
+	}
+	user.MFALastStep = step
+	return nil
+}
+
+// GenerateMFAToken signs the short-lived token proving the password step of
+// a login succeeded. It is not an access token.
+func GenerateMFAToken(user *models.User, exp time.Time) (string, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return "", errors.New("JWT_SECRET not set")
+	}
+	claims := jwt.MapClaims{
+		"typ":    tokenTypeMFA,
+		"userid": user.ID.String(),
+		"ver":    user.TokenVersion,
+		"iss":    jwtIssuer(),
+		"aud":    jwtAudience(),
+		"iat":    time.Now().Unix(),
+		"exp":    exp.Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+// ParseMFAToken returns the user ID and token version of a pending login.
+func ParseMFAToken(tokenString string) (uuid.UUID, int, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return uuid.Nil, 0, err
+	}
+	if claims["typ"] != tokenTypeMFA {
+		return uuid.Nil, 0, errors.New("not an MFA token")
+	}
+	userID, err := uuid.Parse(fmt.Sprint(claims["userid"]))
+	if err != nil {
+		return uuid.Nil, 0, err
+	}
+	ver, ok := claims["ver"].(float64)
+	if !ok {
+		return uuid.Nil, 0, errors.New("invalid token claims")
+	}
+	return userID, int(ver), nil
+}
+
+// backup codes look like "k7dq-9x2m": 8 characters without look-alikes
+const backupCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+func newBackupCode() (string, error) {
+	out := make([]byte, 0, 9)
+	max := big.NewInt(int64(len(backupCodeAlphabet)))
+	for i := 0; i < 8; i++ {
+		if i == 4 {
+			out = append(out, '-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, backupCodeAlphabet[n.Int64()])
+	}
+	return string(out), nil
+}
+
+func normalizeBackupCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// ClearMFA turns 2FA off for the user and deletes the backup codes.
+func ClearMFA(tx *gorm.DB, userID uuid.UUID) error {
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]any{
+		"mfa_secret":     "",
+		"mfa_enabled_at": nil,
+		"mfa_last_step":  0,
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ?", userID).Delete(&models.MFABackupCode{}).Error
+}
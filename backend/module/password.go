@@ -0,0 +1,52 @@
+package module
+
+import (
+	"errors"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password policy shared by registration, change and reset. bcrypt ignores
+// everything after 72 bytes, so longer passwords are rejected.
+const (
+	MinPasswordLength = 8
+	MaxPasswordBytes  = 72
+)
+
+// ValidatePassword checks the password policy: 8 to 72 bytes with at least
+// one letter and one digit, and not equal to the username.
+func ValidatePassword(password, username string) error {
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return errors.New("password must be at least 8 characters")
+	}
+	if len(password) > MaxPasswordBytes {
+		return errors.New("password must be at most 72 bytes")
+	}
+
+	var letter, digit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	if !letter || !digit {
+		return errors.New("password must contain a letter and a digit")
+	}
+	if username != "" && password == username {
+		return errors.New("password must not be the username")
+	}
+	return nil
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
@@ -0,0 +1,64 @@
+package module
+
+import (
+	"errors"
+	"time"
+
+	"Bakery_Pos/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PasswordResetTTL is how long a reset token can be used.
+const PasswordResetTTL = 30 * time.Minute
+
+var ErrResetTokenInvalid = errors.New("reset token is invalid or expired")
+
+// CreatePasswordResetToken issues a reset token for the user. Earlier unused
+// tokens are invalidated so only the latest message works.
+func CreatePasswordResetToken(tx *gorm.DB, userID uuid.UUID, ip string) (string, error) {
+	now := time.Now()
+	if err := tx.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", now).Error; err != nil {
+		return "", err
+	}
+
+	token, err := newTokenSecret()
+	if err != nil {
+		return "", err
+	}
+	reset := models.PasswordResetToken{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		IP:        ip,
+		ExpiresAt: now.Add(PasswordResetTTL),
+	}
+	if err := tx.Create(&reset).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ConsumePasswordResetToken marks the token used and returns its user.
+func ConsumePasswordResetToken(tx *gorm.DB, token string) (uuid.UUID, error) {
+	var reset models.PasswordResetToken
+	if err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(token), time.Now()).
+		First(&reset).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, ErrResetTokenInvalid
+		}
+		return uuid.Nil, err
+	}
+
+	// guarded so two concurrent confirms cannot both use the token
+	res := tx.Model(&reset).Where("used_at IS NULL").Update("used_at", time.Now())
+	if res.Error != nil {
+		return uuid.Nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return uuid.Nil, ErrResetTokenInvalid
+	}
+	return reset.UserID, nil
+}
@@ -0,0 +1,205 @@
+package module
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	"Bakery_Pos/models"
+	"Bakery_Pos/storage"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DataRequestDeadline is how long the shop has to answer a data request.
+const DataRequestDeadline = 30 * 24 * time.Hour
+
+// OpenOrderStatuses are orders still being fulfilled. Deleting an account with
+// one of these waits for an admin, since the order still needs the address.
+var OpenOrderStatuses = []string{"pending", "confirmed", "shipping"}
+
+// WriteUserExport writes a ZIP with one JSON file per kind of data held about
+// the user.
+func WriteUserExport(tx *gorm.DB, userID uuid.UUID, w io.Writer) error {
+	var user models.User
+	if err := tx.Unscoped().Where("id = ?", userID).First(&user).Error; err != nil {
+		return err
+	}
+	var addresses []models.Address
+	if err := tx.Where("user_id = ?", userID).Order("id").Find(&addresses).Error; err != nil {
+		return err
+	}
+	var orders []models.Order
+	if err := tx.Preload("Items").Preload("Payments").Where("user_id = ?", userID).Order("created_at").Find(&orders).Error; err != nil {
+		return err
+	}
+	var cart models.Cart
+	if err := tx.Preload("Items.Product").Where("user_id = ?", userID).Limit(1).Find(&cart).Error; err != nil {
+		return err
+	}
+	var sessions []models.Session
+	if err := tx.Where("user_id = ?", userID).Order("created_at").Find(&sessions).Error; err != nil {
+		return err
+	}
+	var credit []models.StoreCreditEntry
+	if err := tx.Where("user_id = ?", userID).Order("id").Find(&credit).Error; err != nil {
+		return err
+	}
+	var points []models.LoyaltyEntry
+	if err := tx.Where("user_id = ?", userID).Order("id").Find(&points).Error; err != nil {
+		return err
+	}
+
+	profile := struct {
+		models.UserResponse
+		CreatedAt time.Time `json:"created_at"`
+	}{user.ToResponse(), user.CreatedAt}
+	addressData := make([]models.AddressResponse, len(addresses))
+	for i := range addresses {
+		addressData[i] = addresses[i].ToResponse()
+	}
+	orderData := make([]models.OrderResponse, len(orders))
+	for i := range orders {
+		orderData[i] = orders[i].ToResponse()
+	}
+	sessionData := make([]models.SessionResponse, len(sessions))
+	for i := range sessions {
+		sessionData[i] = sessions[i].ToResponse()
+	}
+
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", profile},
+		{"addresses.json", addressData},
+		{"orders.json", orderData},
+		{"cart.json", cart.ToResponse()},
+		{"sessions.json", sessionData},
+		{"store_credit.json", credit},
+		{"loyalty.json", points},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// AnonymizeUser erases the user's personal data. The user row and orders stay
+// so totals and item history still add up in reports; the orders keep only
+// the province of their delivery address. Audit entries about the user keep
+// their action and time but lose their snapshots and IPs. It returns the IDs
+// of orders whose payment slips must be removed from storage once tx commits.
+func AnonymizeUser(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	var user models.User
+	if err := tx.Unscoped().Select("username").Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	anonymous := "deleted-" + userID.String()
+	if err := tx.Unscoped().Model(&models.User{}).Where("id = ?", userID).Updates(map[string]any{
+		"username":       anonymous,
+		"name":           nil,
+		"password":       "", // matches no bcrypt hash, so login always fails
+		"phone_number":   nil,
+		"place":          nil,
+		"mfa_secret":     "",
+		"mfa_enabled_at": nil,
+		"disabled_at":    now,
+		"deleted_at":     now,
+		"token_version":  gorm.Expr("token_version + 1"),
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	var orderIDs []string
+	if err := tx.Model(&models.Order{}).Where("user_id = ?", userID).Pluck("id", &orderIDs).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.Order{}).Where("user_id = ?", userID).Updates(map[string]any{
+		"payment_slip":       "",
+		"shipping_label":     "",
+		"shipping_recipient": "",
+		"shipping_phone":     "",
+		"shipping_line1":     "",
+		"shipping_line2":     "",
+		"shipping_district":  "",
+		"shipping_postcode":  "",
+		"shipping_lat":       nil,
+		"shipping_lng":       nil,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	for _, model := range []any{&models.Address{}, &models.Session{}, &models.MFABackupCode{}, &models.PasswordResetToken{}} {
+		if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&models.Cart{}).Error; err != nil {
+		return nil, err
+	}
+	if err := scrubUserAudit(tx, userID, user.Username, anonymous); err != nil {
+		return nil, err
+	}
+	return orderIDs, nil
+}
+
+// scrubUserAudit blanks the snapshots of entries about the user, the IPs of
+// entries by or about them, and the username in login lockout keys.
+func scrubUserAudit(tx *gorm.DB, userID uuid.UUID, username, anonymous string) error {
+	if err := tx.Model(&models.AuditLog{}).
+		Where("entity_type = ? AND entity_id = ?", "user", userID.String()).
+		Updates(map[string]any{"before": "", "after": "", "ip": ""}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.AuditLog{}).Where("actor_id = ?", userID).Update("ip", "").Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.AuditLog{}).
+		Where("entity_type = ? AND entity_id = ?", "login", UserAttemptKey(username)).
+		Updates(map[string]any{"entity_id": UserAttemptKey(anonymous), "ip": ""}).Error
+}
+
+// RemoveSlips deletes the payment slips of orderIDs. Missing slips are fine;
+// other failures are logged for the storage GC to pick up.
+func RemoveSlips(ctx context.Context, store storage.ObjectStore, orderIDs []string) {
+	for _, id := range orderIDs {
+		if err := store.RemoveFile(ctx, SlipBucket, SlipPath(id)); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("⚠️ Failed to remove slip of order %s: %v", id, err)
+		}
+	}
+}
+
+// NewDataRequest returns a pending request due DataRequestDeadline from now.
+func NewDataRequest(userID uuid.UUID, kind, note string) models.DataRequest {
+	return models.DataRequest{
+		UserID: userID,
+		Type:   kind,
+		Status: models.DataRequestPending,
+		Note:   note,
+		DueAt:  time.Now().Add(DataRequestDeadline),
+	}
+}
+
+// HasOpenOrders reports whether the user has an order still being fulfilled.
+func HasOpenOrders(tx *gorm.DB, userID uuid.UUID) (bool, error) {
+	var count int64
+	err := tx.Model(&models.Order{}).Where("user_id = ? AND status IN ?", userID, OpenOrderStatuses).Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,202 @@
+package module
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"Bakery_Pos/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	ErrSessionInvalid = errors.New("session is invalid or expired")
+	// ErrSessionReused means a rotated refresh token was presented again.
+	ErrSessionReused  = errors.New("refresh token reuse detected")
+	ErrAccountBlocked = errors.New("account is disabled or deleted")
+)
+
+// NewSession stores a session for user and returns it with its refresh token.
+func NewSession(tx *gorm.DB, user *models.User, ip, userAgent string) (models.Session, string, error) {
+	secret, err := newTokenSecret()
+	if err != nil {
+		return models.Session{}, "", err
+	}
+	now := time.Now()
+	session := models.Session{
+		ID:         uuid.New(),
+		UserID:     user.ID,
+		TokenHash:  hashToken(secret),
+		UserAgent:  userAgent,
+		IP:         ip,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(RefreshTokenTTL),
+	}
+	if err := tx.Create(&session).Error; err != nil {
+		return models.Session{}, "", err
+	}
+	return session, session.ID.String() + "." + secret, nil
+}
+
+// RotateSession exchanges a refresh token for a new one. Presenting the token
+// that was rotated out revokes the session, since one of the two holders is
+// not the user.
+func RotateSession(tx *gorm.DB, refreshToken, ip, userAgent string) (models.Session, string, error) {
+	id, secret, ok := strings.Cut(refreshToken, ".")
+	sessionID, err := uuid.Parse(id)
+	if !ok || err != nil || secret == "" {
+		return models.Session{}, "", ErrSessionInvalid
+	}
+
+	var session models.Session
+	if err := tx.Where("id = ?", sessionID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Session{}, "", ErrSessionInvalid
+		}
+		return models.Session{}, "", err
+	}
+	if !session.Active() {
+		return models.Session{}, "", ErrSessionInvalid
+	}
+
+	hash := hashToken(secret)
+	if session.PrevHash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(session.PrevHash)) == 1 {
+		now := time.Now()
+		if err := tx.Model(&session).Update("revoked_at", &now).Error; err != nil {
+			return models.Session{}, "", err
+		}
+		return models.Session{}, "", ErrSessionReused
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(session.TokenHash)) != 1 {
+		return models.Session{}, "", ErrSessionInvalid
+	}
+
+	next, err := newTokenSecret()
+	if err != nil {
+		return models.Session{}, "", err
+	}
+	// the hash guard makes concurrent refreshes with the same token fail
+	// instead of both succeeding
+	res := tx.Model(&models.Session{}).
+		Where("id = ? AND token_hash = ?", session.ID, session.TokenHash).
+		Updates(map[string]any{
+			"prev_hash":    session.TokenHash,
+			"token_hash":   hashToken(next),
+			"ip":           ip,
+			"user_agent":   userAgent,
+			"last_used_at": time.Now(),
+		})
+	if res.Error != nil {
+		return models.Session{}, "", res.Error
+	}
+	if res.RowsAffected == 0 {
+		return models.Session{}, "", ErrSessionInvalid
+	}
+	return session, session.ID.String() + "." + next, nil
+}
+
+// EndSession revokes the session a refresh token belongs to, for logout. The
+// token must be the session's current one; anything else is ErrSessionInvalid
+// and changes nothing.
+func EndSession(tx *gorm.DB, refreshToken string) error {
+	id, secret, ok := strings.Cut(refreshToken, ".")
+	sessionID, err := uuid.Parse(id)
+	if !ok || err != nil || secret == "" {
+		return ErrSessionInvalid
+	}
+
+	var session models.Session
+	if err := tx.Where("id = ? AND revoked_at IS NULL", sessionID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionInvalid
+		}
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(session.TokenHash)) != 1 {
+		return ErrSessionInvalid
+	}
+	return tx.Model(&models.Session{}).
+		Where("id = ? AND token_hash = ? AND revoked_at IS NULL", session.ID, session.TokenHash).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeSession ends one of the user's sessions.
+func RevokeSession(tx *gorm.DB, userID, sessionID uuid.UUID) error {
+	res := tx.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeAllSessions ends every session of the user and bumps the token
+// version, so access tokens already handed out stop working too.
+func RevokeAllSessions(tx *gorm.DB, userID uuid.UUID) error {
+	if err := tx.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.User{}).Where("id = ?", userID).
+		Update("token_version", gorm.Expr("token_version + 1")).Error
+}
+
+// CheckAccess verifies that an access token for userID, session sessionID and
+// token version version is still valid, and returns the current user.
+func CheckAccess(tx *gorm.DB, userID, sessionID uuid.UUID, version int) (models.User, error) {
+	var user models.User
+	if err := tx.Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, ErrAccountBlocked
+		}
+		return user, err
+	}
+	if user.DisabledAt != nil {
+		return user, ErrAccountBlocked
+	}
+	if user.TokenVersion != version {
+		return user, ErrSessionInvalid
+	}
+
+	var session models.Session
+	if err := tx.Select("id", "revoked_at", "expires_at").Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, ErrSessionInvalid
+		}
+		return user, err
+	}
+	if !session.Active() {
+		return user, ErrSessionInvalid
+	}
+	return user, nil
+}
+
+func newTokenSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,272 @@
+package module
+
+import (
+	"errors"
+	"time"
+
+	"Bakery_Pos/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrShiftAlreadyOpen = errors.New("cashier already has an open shift")
+	ErrNoOpenShift      = errors.New("no open shift")
+	ErrShiftClosed      = errors.New("shift is closed")
+)
+
+// OpenShift starts a shift for the cashier with float in the drawer.
+func OpenShift(tx *gorm.DB, cashierID uuid.UUID, float float64, register string) (models.Shift, error) {
+	shift := models.Shift{
+		CashierID:    cashierID,
+		Register:     register,
+		Status:       models.ShiftOpen,
+		OpeningFloat: roundBaht(float),
+		OpenedAt:     time.Now(),
+	}
+	if err := lockUser(tx, cashierID); err != nil {
+		return shift, err
+	}
+	if _, err := CurrentShift(tx, cashierID); err == nil {
+		return shift, ErrShiftAlreadyOpen
+	} else if !errors.Is(err, ErrNoOpenShift) {
+		return shift, err
+	}
+	return shift, tx.Create(&shift).Error
+}
+
+// CurrentShift returns the cashier's open shift.
+func CurrentShift(tx *gorm.DB, cashierID uuid.UUID) (models.Shift, error) {
+	var shift models.Shift
+	err := tx.Where("cashier_id = ? AND status = ?", cashierID, models.ShiftOpen).First(&shift).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = ErrNoOpenShift
+	}
+	return shift, err
+}
+
+// AddCashMovement records cash put into or taken out of an open shift's drawer.
+func AddCashMovement(tx *gorm.DB, shiftID uint, kind string, amount float64, reason string, actorID *uuid.UUID) (models.CashMovement, error) {
+	movement := models.CashMovement{
+		ShiftID: shiftID,
+		Kind:    kind,
+		Amount:  roundBaht(amount),
+		Reason:  reason,
+		ActorID: actorID,
+	}
+	if _, err := LockOpenShift(tx, shiftID); err != nil {
+		return movement, err
+	}
+	return movement, tx.Create(&movement).Error
+}
+
+// ExpectedCash is what the drawer of shift should hold: the opening float,
+// plus cash taken on its sales that was not given back on a void, plus cash
+// in, less cash out.
+func ExpectedCash(tx *gorm.DB, shift *models.Shift) (float64, error) {
+	var sales float64
+	if err := tx.Model(&models.OrderPayment{}).
+		Joins("JOIN orders ON orders.id = order_payments.order_id").
+		Where("orders.shift_id = ? AND order_payments.method = ? AND order_payments.refunded_at IS NULL", shift.ID, models.TenderCash).
+		Select("COALESCE(SUM(order_payments.amount), 0)").Scan(&sales).Error; err != nil {
+		return 0, err
+	}
+	in, out, err := cashMovementTotals(tx.Where("shift_id = ?", shift.ID))
+	if err != nil {
+		return 0, err
+	}
+	return roundBaht(shift.OpeningFloat + sales + in - out), nil
+}
+
+// CloseShift records the counted cash of an open shift and the over/short
+// against the expected cash.
+func CloseShift(tx *gorm.DB, shiftID uint, counted float64, note string, actorID *uuid.UUID) (models.Shift, error) {
+	shift, err := LockOpenShift(tx, shiftID)
+	if err != nil {
+		return shift, err
+	}
+	expected, err := ExpectedCash(tx, &shift)
+	if err != nil {
+		return shift, err
+	}
+	now := time.Now()
+	counted = roundBaht(counted)
+	overShort := roundBaht(counted - expected)
+	shift.Status = models.ShiftClosed
+	shift.ClosedAt = &now
+	shift.ClosedBy = actorID
+	shift.ExpectedCash = &expected
+	shift.CountedCash = &counted
+	shift.OverShort = &overShort
+	shift.CloseNote = note
+	return shift, tx.Save(&shift).Error
+}
+
+// BuildShiftReport sums up a shift's sales and cash movements. The expected
+// cash of a closed shift is the one recorded when it was closed.
+func BuildShiftReport(tx *gorm.DB, shift models.Shift) (models.ShiftReport, error) {
+	report := models.ShiftReport{Shift: shift, Movements: []models.CashMovement{}}
+
+	var cashier models.User
+	if err := tx.Unscoped().Select("id", "name", "username").Where("id = ?", shift.CashierID).First(&cashier).Error; err == nil {
+		report.Cashier = cashier.Username
+		if cashier.Name != nil && *cashier.Name != "" {
+			report.Cashier = *cashier.Name
+		}
+	}
+
+	var err error
+	// a sale can only be voided while its shift is open, so the shift's
+	// refunds are those of its own sales
+	byShift := func(q *gorm.DB) *gorm.DB {
+		return q.Where("orders.shift_id = ?", shift.ID)
+	}
+	if report.Sales, err = SalesSummaryFor(tx, byShift, byShift); err != nil {
+		return report, err
+	}
+	if err := tx.Where("shift_id = ?", shift.ID).Order("id").Find(&report.Movements).Error; err != nil {
+		return report, err
+	}
+	for _, m := range report.Movements {
+		if m.Kind == models.CashIn {
+			report.CashIn += m.Amount
+		} else {
+			report.CashOut += m.Amount
+		}
+	}
+	report.CashIn, report.CashOut = roundBaht(report.CashIn), roundBaht(report.CashOut)
+
+	if shift.ExpectedCash != nil {
+		report.ExpectedCash = *shift.ExpectedCash
+	} else if report.ExpectedCash, err = ExpectedCash(tx, &shift); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// BuildZReport sums up every order placed in [start, end) and the shifts
+// opened in it.
+func BuildZReport(tx *gorm.DB, start, end time.Time) (models.ZReport, error) {
+	report := models.ZReport{Date: start.Format("2006-01-02"), Shifts: []models.ShiftReport{}}
+
+	var err error
+	if report.Sales, err = SalesSummaryFor(tx, func(q *gorm.DB) *gorm.DB {
+		return q.Where("orders.created_at >= ? AND orders.created_at < ?", start, end)
+	}, func(q *gorm.DB) *gorm.DB {
+		// refunds count on the day they were given, whenever the order was placed
+		return q.Where("order_payments.refunded_at >= ? AND order_payments.refunded_at < ?", start, end)
+	}); err != nil {
+		return report, err
+	}
+
+	var shifts []models.Shift
+	if err := tx.Where("opened_at >= ? AND opened_at < ?", start, end).Order("opened_at").Find(&shifts).Error; err != nil {
+		return report, err
+	}
+	for _, shift := range shifts {
+		sr, err := BuildShiftReport(tx, shift)
+		if err != nil {
+			return report, err
+		}
+		report.Shifts = append(report.Shifts, sr)
+		report.CashIn += sr.CashIn
+		report.CashOut += sr.CashOut
+		if shift.OverShort != nil {
+			report.OverShort += *shift.OverShort
+		}
+		if shift.Status == models.ShiftOpen {
+			report.OpenShifts++
+		}
+	}
+	report.CashIn = roundBaht(report.CashIn)
+	report.CashOut = roundBaht(report.CashOut)
+	report.OverShort = roundBaht(report.OverShort)
+	return report, nil
+}
+
+// SalesSummaryFor sums up the orders scope selects and the refunds
+// refundScope selects. Online orders count what the tenders left as paid by
+// transfer.
+func SalesSummaryFor(tx *gorm.DB, scope, refundScope func(*gorm.DB) *gorm.DB) (models.SalesSummary, error) {
+	summary := models.SalesSummary{Tenders: []models.TenderTotal{}, Refunds: []models.TenderTotal{}}
+	orders := func() *gorm.DB { return scope(tx.Table("orders")) }
+
+	var totals struct {
+		Orders    int64
+		NetSales  float64
+		Discounts float64
+		Transfers int64
+		Transfer  float64
+	}
+	if err := orders().Where("orders.status <> 'cancelled'").Select(`COUNT(*) AS orders,
+		COALESCE(SUM(orders.total), 0) AS net_sales,
+		COALESCE(SUM(orders.item_discount + COALESCE(orders.points_discount, 0)), 0) AS discounts,
+		COUNT(*) FILTER (WHERE orders.channel = 'online' AND orders.total > orders.tender_total) AS transfers,
+		COALESCE(SUM(orders.total - orders.tender_total) FILTER (WHERE orders.channel = 'online'), 0) AS transfer`).
+		Scan(&totals).Error; err != nil {
+		return summary, err
+	}
+	summary.Orders = totals.Orders
+	summary.NetSales = roundBaht(totals.NetSales)
+	summary.Discounts = roundBaht(totals.Discounts)
+	summary.GrossSales = roundBaht(totals.NetSales + totals.Discounts)
+
+	var voids struct {
+		Count  int64
+		Amount float64
+	}
+	if err := orders().Where("orders.status = 'cancelled'").
+		Select("COUNT(*) AS count, COALESCE(SUM(orders.total), 0) AS amount").Scan(&voids).Error; err != nil {
+		return summary, err
+	}
+	summary.Voids = voids.Count
+	summary.VoidedAmount = roundBaht(voids.Amount)
+
+	payments := func(scope func(*gorm.DB) *gorm.DB) *gorm.DB {
+		return scope(tx.Table("order_payments").Joins("JOIN orders ON orders.id = order_payments.order_id")).
+			Select("order_payments.method, COUNT(*) AS count, SUM(order_payments.amount) AS amount").
+			Group("order_payments.method").Order("order_payments.method")
+	}
+	if err := payments(scope).Where("orders.status <> 'cancelled'").Scan(&summary.Tenders).Error; err != nil {
+		return summary, err
+	}
+	if totals.Transfer > 0 {
+		summary.Tenders = append(summary.Tenders, models.TenderTotal{Method: "transfer", Count: totals.Transfers, Amount: totals.Transfer})
+	}
+	if err := payments(refundScope).Where("order_payments.refunded_at IS NOT NULL").Scan(&summary.Refunds).Error; err != nil {
+		return summary, err
+	}
+	for i := range summary.Tenders {
+		summary.Tenders[i].Amount = roundBaht(summary.Tenders[i].Amount)
+	}
+	for i := range summary.Refunds {
+		summary.Refunds[i].Amount = roundBaht(summary.Refunds[i].Amount)
+	}
+	return summary, nil
+}
+
+func cashMovementTotals(q *gorm.DB) (float64, float64, error) {
+	var totals struct {
+		In  float64
+		Out float64
+	}
+	err := q.Model(&models.CashMovement{}).Select(`COALESCE(SUM(amount) FILTER (WHERE kind = 'in'), 0) AS "in",
+		COALESCE(SUM(amount) FILTER (WHERE kind = 'out'), 0) AS "out"`).Scan(&totals).Error
+	return totals.In, totals.Out, err
+}
+
+// LockOpenShift locks the shift so it cannot close while a sale or void is
+// recorded on it. It fails with ErrShiftClosed once the shift is closed.
+func LockOpenShift(tx *gorm.DB, shiftID uint) (models.Shift, error) {
+	var shift models.Shift
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&shift, shiftID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return shift, ErrNoOpenShift
+	}
+	if err == nil && shift.Status != models.ShiftOpen {
+		err = ErrShiftClosed
+	}
+	return shift, err
+}
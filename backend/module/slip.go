@@ -0,0 +1,30 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"Bakery_Pos/storage"
+)
+
+// SlipBucket is the private bucket holding payment slips.
+const SlipBucket = "order-slips"
+
+// SlipURLTTL is how long a signed slip download URL stays valid.
+const SlipURLTTL = 10 * time.Minute
+
+// SlipPath is the object path of an order's payment slip.
+func SlipPath(orderID string) string {
+	return fmt.Sprintf("orders/%s/%s", orderID, "slip.png")
+}
+
+// SignSlipURL returns a download URL for the order's slip valid for
+// SlipURLTTL, or an error wrapping storage.ErrNotFound when none has been
+// uploaded; stores sign URLs for missing objects too.
+func SignSlipURL(ctx context.Context, store storage.ObjectStore, orderID string) (string, error) {
+	if _, err := store.Stat(ctx, SlipBucket, SlipPath(orderID)); err != nil {
+		return "", err
+	}
+	return store.GenerateDownloadURL(ctx, SlipBucket, SlipPath(orderID), SlipURLTTL)
+}
@@ -0,0 +1,124 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"Bakery_Pos/models"
+	"Bakery_Pos/storage"
+
+	"gorm.io/gorm"
+)
+
+// DefaultStorageGCGrace keeps fresh objects whose row may not be committed yet,
+// e.g. an upload racing the transaction that creates its image.
+const DefaultStorageGCGrace = 24 * time.Hour
+
+// CollectStorageGarbage compares the objects in the product image and slip
+// buckets with the rows referencing them. Objects nothing references, or only
+// images of products deleted more than grace ago, are orphans and are deleted
+// once older than grace, unless dryRun is set. Ready images whose files are
+// missing are reported as dangling but left alone.
+func CollectStorageGarbage(ctx context.Context, tx *gorm.DB, store storage.ObjectStore, grace time.Duration, dryRun bool) (models.StorageGCResponse, error) {
+	resp := models.StorageGCResponse{
+		DryRun:         dryRun,
+		GraceHours:     grace.Hours(),
+		Orphans:        []models.StorageOrphan{},
+		DanglingImages: []models.DanglingImage{},
+	}
+
+	// images of products deleted longer than grace ago no longer count, so
+	// their files are collected; a product can still be restored until then
+	var images []models.Image
+	if err := tx.Select("images.*").
+		Joins("JOIN products ON products.id = images.product_id").
+		Where("products.deleted_at IS NULL OR products.deleted_at > ?", time.Now().Add(-grace)).
+		Find(&images).Error; err != nil {
+		return resp, fmt.Errorf("failed to load images: %w", err)
+	}
+	var orderIDs []string
+	if err := tx.Model(&models.Order{}).Pluck("id", &orderIDs).Error; err != nil {
+		return resp, fmt.Errorf("failed to load orders: %w", err)
+	}
+
+	imageRefs := make(map[string]bool)
+	for _, img := range images {
+		for _, p := range []string{img.FilePath, img.ThumbnailPath, img.MediumPath} {
+			if p != "" {
+				imageRefs[p] = true
+			}
+		}
+	}
+	slipRefs := make(map[string]bool, len(orderIDs))
+	for _, id := range orderIDs {
+		slipRefs[SlipPath(id)] = true
+	}
+
+	imageObjects, err := collectOrphans(ctx, store, ProductImageBucket, imageRefs, grace, dryRun, &resp)
+	if err != nil {
+		return resp, err
+	}
+	if _, err := collectOrphans(ctx, store, SlipBucket, slipRefs, grace, dryRun, &resp); err != nil {
+		return resp, err
+	}
+
+	for _, img := range images {
+		if img.Status != models.ImageStatusReady {
+			continue
+		}
+		var missing []string
+		for _, p := range []string{img.FilePath, img.ThumbnailPath, img.MediumPath} {
+			if p != "" && !imageObjects[p] {
+				missing = append(missing, p)
+			}
+		}
+		if len(missing) > 0 {
+			resp.DanglingImages = append(resp.DanglingImages, models.DanglingImage{
+				ImageID:   img.ID,
+				ProductID: img.ProductID,
+				Missing:   missing,
+			})
+		}
+	}
+
+	return resp, nil
+}
+
+// collectOrphans lists bucket, records unreferenced objects in resp and removes
+// those past the grace period. It returns the set of paths present in the bucket.
+func collectOrphans(ctx context.Context, store storage.ObjectStore, bucket string, refs map[string]bool, grace time.Duration, dryRun bool, resp *models.StorageGCResponse) (map[string]bool, error) {
+	objects, err := store.List(ctx, bucket, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", bucket, err)
+	}
+
+	cutoff := time.Now().Add(-grace)
+	present := make(map[string]bool, len(objects))
+	for _, obj := range objects {
+		present[obj.Path] = true
+		resp.Scanned++
+		if refs[obj.Path] {
+			continue
+		}
+
+		orphan := models.StorageOrphan{
+			Bucket:    bucket,
+			Path:      obj.Path,
+			Size:      obj.Size,
+			UpdatedAt: obj.UpdatedAt,
+			InGrace:   obj.UpdatedAt.After(cutoff),
+		}
+		if !dryRun && !orphan.InGrace {
+			if err := store.RemoveFile(ctx, bucket, obj.Path); err != nil {
+				resp.Errors = append(resp.Errors, fmt.Sprintf("%s/%s: %v", bucket, obj.Path, err))
+			} else {
+				orphan.Deleted = true
+				resp.Deleted++
+				resp.FreedBytes += obj.Size
+			}
+		}
+		resp.Orphans = append(resp.Orphans, orphan)
+	}
+	return present, nil
+}
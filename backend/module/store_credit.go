@@ -0,0 +1,153 @@
+package module
+
+import (
+	"errors"
+	"math"
+
+	"Bakery_Pos/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrInsufficientCredit = errors.New("not enough store credit")
+
+// roundBaht rounds an amount to whole satang.
+func roundBaht(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// StoreCreditBalance returns the user's store credit in baht.
+func StoreCreditBalance(tx *gorm.DB, userID uuid.UUID) (float64, error) {
+	var balance float64
+	err := tx.Model(&models.StoreCreditEntry{}).Where("user_id = ?", userID).
+		Select("COALESCE(SUM(amount), 0)").Scan(&balance).Error
+	return roundBaht(balance), err
+}
+
+// IssueStoreCredit adds amount to the user's wallet, e.g. for a refund.
+func IssueStoreCredit(tx *gorm.DB, userID uuid.UUID, amount float64, reason string, orderID *string, actorID *uuid.UUID) (models.StoreCreditEntry, error) {
+	entry := models.StoreCreditEntry{
+		UserID:  userID,
+		OrderID: orderID,
+		Kind:    models.StoredValueIssue,
+		Amount:  roundBaht(amount),
+		Reason:  reason,
+		ActorID: actorID,
+	}
+	return entry, postStoreCredit(tx, &entry)
+}
+
+// VoidStoreCredit removes amount from the user's wallet, or the whole balance
+// when amount is 0.
+func VoidStoreCredit(tx *gorm.DB, userID uuid.UUID, amount float64, reason string, actorID *uuid.UUID) (models.StoreCreditEntry, error) {
+	entry := models.StoreCreditEntry{
+		UserID:  userID,
+		Kind:    models.StoredValueVoid,
+		Amount:  -roundBaht(amount),
+		Reason:  reason,
+		ActorID: actorID,
+	}
+	if amount == 0 {
+		if err := lockUser(tx, userID); err != nil {
+			return entry, err
+		}
+		balance, err := StoreCreditBalance(tx, userID)
+		if err != nil {
+			return entry, err
+		}
+		if balance <= 0 {
+			return entry, ErrInsufficientCredit
+		}
+		entry.Amount = -balance
+	}
+	return entry, postStoreCredit(tx, &entry)
+}
+
+// RedeemStoreCredit pays amount of order with the customer's store credit.
+func RedeemStoreCredit(tx *gorm.DB, order *models.Order, amount float64) (models.OrderPayment, error) {
+	payment := models.OrderPayment{
+		OrderID: order.ID,
+		Method:  models.TenderStoreCredit,
+		Amount:  roundBaht(amount),
+	}
+	if order.UserID == nil {
+		return payment, ErrInsufficientCredit
+	}
+	if err := postStoreCredit(tx, &models.StoreCreditEntry{
+		UserID:  *order.UserID,
+		OrderID: &order.ID,
+		Kind:    models.StoredValueRedeem,
+		Amount:  -payment.Amount,
+	}); err != nil {
+		return payment, err
+	}
+	return payment, tx.Create(&payment).Error
+}
+
+// postStoreCredit records entry under the user lock. A debit cannot take the
+// balance below zero.
+func postStoreCredit(tx *gorm.DB, entry *models.StoreCreditEntry) error {
+	if err := lockUser(tx, entry.UserID); err != nil {
+		return err
+	}
+	balance, err := StoreCreditBalance(tx, entry.UserID)
+	if err != nil {
+		return err
+	}
+	entry.BalanceAfter = roundBaht(balance + entry.Amount)
+	if entry.Amount < 0 && entry.BalanceAfter < 0 {
+		return ErrInsufficientCredit
+	}
+	return tx.Create(entry).Error
+}
+
+// StoreCreditSummary returns the user's balance and a page of the wallet
+// history, newest first.
+func StoreCreditSummary(tx *gorm.DB, userID uuid.UUID, page, limit int) (models.StoreCreditResponse, error) {
+	resp := models.StoreCreditResponse{
+		History: []models.StoreCreditEntry{},
+		Page:    page,
+		Limit:   limit,
+	}
+
+	var err error
+	if resp.Balance, err = StoreCreditBalance(tx, userID); err != nil {
+		return resp, err
+	}
+	history := tx.Model(&models.StoreCreditEntry{}).Where("user_id = ?", userID)
+	if err := history.Count(&resp.Total).Error; err != nil {
+		return resp, err
+	}
+	err = history.Order("created_at DESC, id DESC").Limit(limit).Offset((page - 1) * limit).Find(&resp.History).Error
+	return resp, err
+}
+
+// PayWithTenders pays order from the customer's store credit, up to
+// storeCredit baht, and then from the gift cards in the given order, each
+// paying what it can of the rest. Cards that are not needed are not charged.
+// It sets the payments and TenderTotal on order.
+func PayWithTenders(tx *gorm.DB, order *models.Order, storeCredit float64, giftCards []string) error {
+	due := roundBaht(order.Total - order.TenderTotal)
+	if storeCredit > 0 && due > 0 {
+		payment, err := RedeemStoreCredit(tx, order, min(storeCredit, due))
+		if err != nil {
+			return err
+		}
+		order.Payments = append(order.Payments, payment)
+		due = roundBaht(due - payment.Amount)
+	}
+	for _, code := range giftCards {
+		if due <= 0 {
+			break
+		}
+		payment, err := RedeemGiftCard(tx, order, code, due)
+		if err != nil {
+			return err
+		}
+		order.Payments = append(order.Payments, payment)
+		due = roundBaht(due - payment.Amount)
+	}
+	order.TenderTotal = roundBaht(order.Total - due)
+	return tx.Model(order).UpdateColumn("tender_total", order.TenderTotal).Error
+}
@@ -0,0 +1,97 @@
+package module
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"Bakery_Pos/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TierWindow is the spend period tiers are based on.
+const TierWindow = 12 // months
+
+// TierPricingFor returns the pricing of a member of tier, nil for none.
+// TIER_DISCOUNT_STACKING=stack takes the tier discount off promotional
+// prices; by default the larger discount applies.
+func TierPricingFor(tier *models.MembershipTier) models.TierPricing {
+	if tier == nil {
+		return models.TierPricing{}
+	}
+	return models.TierPricing{
+		DiscountPercent: tier.DiscountPercent,
+		Stack:           os.Getenv("TIER_DISCOUNT_STACKING") == "stack",
+	}
+}
+
+// UserTier returns the user's membership tier, or nil.
+func UserTier(tx *gorm.DB, userID uuid.UUID) (*models.MembershipTier, error) {
+	var user models.User
+	if err := tx.Preload("Tier").Select("id", "tier_id").Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return user.Tier, nil
+}
+
+// DeliveryFee is charged on orders with a delivery address, unless the tier
+// delivers free. It is DELIVERY_FEE baht, 0 by default.
+func DeliveryFee(tier *models.MembershipTier) float64 {
+	if tier != nil && tier.FreeDelivery {
+		return 0
+	}
+	fee, err := strconv.ParseFloat(os.Getenv("DELIVERY_FEE"), 64)
+	if err != nil || fee < 0 {
+		return 0
+	}
+	return fee
+}
+
+// SeedTiers creates the default tiers when there are none.
+func SeedTiers(tx *gorm.DB) error {
+	var count int64
+	if err := tx.Model(&models.MembershipTier{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	tiers := append([]models.MembershipTier(nil), models.DefaultTiers...)
+	return tx.Create(&tiers).Error
+}
+
+// RecalculateTiers sets the spend over the last TierWindow months and the
+// matching tier of every user, or only of userID when given. Unpaid and
+// cancelled orders do not count. It returns the number of users whose tier
+// changed.
+func RecalculateTiers(tx *gorm.DB, userID *uuid.UUID, now time.Time) (int64, error) {
+	since := now.AddDate(0, -TierWindow, 0)
+	scope := func(q *gorm.DB) *gorm.DB {
+		if userID != nil {
+			return q.Where("id = ?", *userID)
+		}
+		return q
+	}
+
+	if err := scope(tx.Model(&models.User{})).Where("1 = 1").UpdateColumns(map[string]any{
+		"tier_spend": gorm.Expr(`COALESCE((SELECT SUM(orders.total) FROM orders
+			WHERE orders.user_id = users.id AND orders.created_at >= ?
+			AND orders.status NOT IN ('pending', 'cancelled')), 0)`, since),
+		"tier_updated_at": now,
+	}).Error; err != nil {
+		return 0, err
+	}
+
+	res := scope(tx.Model(&models.User{})).
+		Where(`tier_id IS DISTINCT FROM (SELECT t.id FROM membership_tiers t
+			WHERE t.min_spend <= users.tier_spend ORDER BY t.min_spend DESC LIMIT 1)`).
+		UpdateColumn("tier_id", gorm.Expr(`(SELECT t.id FROM membership_tiers t
+			WHERE t.min_spend <= users.tier_spend ORDER BY t.min_spend DESC LIMIT 1)`))
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,72 @@
+package module
+
+import (
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B, SHA-1. The RFC lists 8 digit codes; ours are the
+// last 6 digits of the same value.
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := totpStep(now)
+	codeAt := func(s int64) string {
+		code, err := hotp(rfc6238Secret, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", "050471", 0, step, true},
+		{"spaces are ignored", " 050 471 ", 0, step, true},
+		{"previous step within skew", codeAt(step - 1), 0, step - 1, true},
+		{"next step within skew", codeAt(step + 1), 0, step + 1, true},
+		{"outside skew", codeAt(step - 2), 0, 0, false},
+		{"wrong code", "000000", 0, 0, false},
+		{"wrong length", "05047", 0, 0, false},
+		{"replay of the last used step", "050471", step, 0, false},
+		{"step older than the last used one", codeAt(step - 1), step, 0, false},
+		{"later step after an earlier one was used", codeAt(step + 1), step, step + 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := ValidateTOTP(rfc6238Secret, tt.code, now, tt.lastStep)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("ValidateTOTP(%q, last %d) = %d, %v; want %d, %v", tt.code, tt.lastStep, gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
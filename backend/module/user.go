@@ -7,18 +7,55 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-func GenerateJWT(user *models.User, exp time.Time) (string, error) {
+// Defaults for the iss and aud claims, overridable with JWT_ISSUER and
+// JWT_AUDIENCE so several deployments sharing a secret don't accept each
+// other's tokens.
+const (
+	defaultJWTIssuer   = "bakery-pos"
+	defaultJWTAudience = "bakery-pos-api"
+)
+
+// The typ claim keeps the pending MFA token from being used as an access token.
+const (
+	tokenTypeAccess = "access"
+	tokenTypeMFA    = "mfa"
+)
+
+func jwtIssuer() string {
+	if v := os.Getenv("JWT_ISSUER"); v != "" {
+		return v
+	}
+	return defaultJWTIssuer
+}
+
+func jwtAudience() string {
+	if v := os.Getenv("JWT_AUDIENCE"); v != "" {
+		return v
+	}
+	return defaultJWTAudience
+}
+
+// GenerateJWT signs an access token for user's session. ver is checked
+// against User.TokenVersion on every request.
+func GenerateJWT(user *models.User, sessionID uuid.UUID, exp time.Time) (string, error) {
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
 		return "", errors.New("JWT_SECRET not set")
 	}
 
 	claims := jwt.MapClaims{
+		"typ":      tokenTypeAccess,
 		"userid":   user.ID.String(),
 		"username": user.Username,
 		"role":     user.Role,
+		"sid":      sessionID.String(),
+		"ver":      user.TokenVersion,
+		"iss":      jwtIssuer(),
+		"aud":      jwtAudience(),
+		"iat":      time.Now().Unix(),
 		"exp":      exp.Unix(),
 	}
 
@@ -29,3 +66,38 @@ func GenerateJWT(user *models.User, exp time.Time) (string, error) {
 	}
 	return tokenString, nil
 }
+
+// ParseJWT verifies an access token issued by GenerateJWT. Only HS256 is
+// accepted, and exp, iss and aud must be present and match.
+func ParseJWT(tokenString string) (jwt.MapClaims, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims["typ"] != tokenTypeAccess {
+		return nil, errors.New("not an access token")
+	}
+	return claims, nil
+}
+
+func parseClaims(tokenString string) (jwt.MapClaims, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, errors.New("JWT_SECRET not set")
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(jwtIssuer()),
+		jwt.WithAudience(jwtAudience()),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
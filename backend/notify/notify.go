@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Message is a notification for one recipient. To is whatever the sender
+// understands, currently the username or phone number.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages to users. Real senders (SMS, LINE, e-mail) plug
+// in here; the log and file senders are meant for development.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogNotifier writes messages to the server log. The body is left out since
+// it can carry reset links; use the file sender to read it in development.
+type LogNotifier struct{}
+
+func (LogNotifier) Send(_ context.Context, msg Message) error {
+	log.Printf("📨 To %s: %s (%d bytes, body not logged)", msg.To, msg.Subject, len(msg.Body))
+	return nil
+}
+
+// FileNotifier writes every message to its own text file in Dir.
+type FileNotifier struct {
+	Dir string
+}
+
+func (n FileNotifier) Send(_ context.Context, msg Message) error {
+	if err := os.MkdirAll(n.Dir, 0o700); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.txt", time.Now().Format("20060102-150405.000000"), sanitize(msg.To))
+	content := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", msg.To, msg.Subject, msg.Body)
+	return os.WriteFile(filepath.Join(n.Dir, name), []byte(content), 0o600)
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// Default is the notifier handlers use, set by Setup.
+var Default Notifier = LogNotifier{}
+
+// Setup selects the notifier from NOTIFIER: log (default) or file, which
+// writes to NOTIFIER_DIR (default ./outbox).
+func Setup() {
+	switch os.Getenv("NOTIFIER") {
+	case "", "log":
+		Default = LogNotifier{}
+	case "file":
+		dir := os.Getenv("NOTIFIER_DIR")
+		if dir == "" {
+			dir = "./outbox"
+		}
+		Default = FileNotifier{Dir: dir}
+	default:
+		log.Fatalf("Unknown NOTIFIER %q", os.Getenv("NOTIFIER"))
+	}
+}
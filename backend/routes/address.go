@@ -0,0 +1,215 @@
+package routes
+
+import (
+	"errors"
+	"strconv"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetAddresses godoc
+// @Summary List saved delivery addresses
+// @Description The default address comes first
+// @Tags user
+// @Produce json
+// @Success 200 {array} models.AddressResponse
+// @Router /user/addresses [get]
+// @Security BearerAuth
+func GetAddresses(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userid").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	addresses, err := loadAddresses(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch addresses"})
+	}
+	return c.Status(fiber.StatusOK).JSON(addresses)
+}
+
+// CreateAddress godoc
+// @Summary Save a delivery address
+// @Description The first address becomes the default
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body models.BodyAddressRequest true "Address"
+// @Success 201 {object} models.AddressResponse
+// @Router /user/addresses [post]
+// @Security BearerAuth
+func CreateAddress(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userid").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	var body models.BodyAddressRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	if err := module.NormalizeAddress(&body.AddressDetails); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	addr := models.Address{
+		UserID:         userID,
+		AddressDetails: body.AddressDetails,
+		IsDefault:      body.IsDefault,
+	}
+	errTooMany := errors.New("too many addresses")
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Address{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= models.MaxAddresses {
+			return errTooMany
+		}
+		if count == 0 {
+			addr.IsDefault = true
+		}
+		if err := tx.Create(&addr).Error; err != nil {
+			return err
+		}
+		if addr.IsDefault {
+			return module.MakeDefaultAddress(tx, userID, addr.ID)
+		}
+		return nil
+	})
+	if errors.Is(err, errTooMany) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "You can save at most " + strconv.Itoa(models.MaxAddresses) + " addresses",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save address"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(addr.ToResponse())
+}
+
+// UpdateAddress godoc
+// @Summary Replace a saved delivery address
+// @Description Orders already placed keep the address they were placed with
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param address_id path int true "Address ID"
+// @Param request body models.BodyAddressRequest true "Address"
+// @Success 200 {object} models.AddressResponse
+// @Router /user/addresses/{address_id} [put]
+// @Security BearerAuth
+func UpdateAddress(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userid").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	addressID, err := c.ParamsInt("address_id")
+	if err != nil || addressID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid address ID"})
+	}
+
+	var body models.BodyAddressRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	if err := module.NormalizeAddress(&body.AddressDetails); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var addr models.Address
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND user_id = ?", addressID, userID).First(&addr).Error; err != nil {
+			return err
+		}
+		addr.AddressDetails = body.AddressDetails
+		// the default can be moved to another address, not unset
+		addr.IsDefault = addr.IsDefault || body.IsDefault
+		if err := tx.Save(&addr).Error; err != nil {
+			return err
+		}
+		if addr.IsDefault {
+			return module.MakeDefaultAddress(tx, userID, addr.ID)
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Address not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update address"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(addr.ToResponse())
+}
+
+// DeleteAddress godoc
+// @Summary Delete a saved delivery address
+// @Description When the default is deleted, the most recently updated remaining address becomes the default
+// @Tags user
+// @Produce json
+// @Param address_id path int true "Address ID"
+// @Success 200 {object} models.MessageResponse
+// @Router /user/addresses/{address_id} [delete]
+// @Security BearerAuth
+func DeleteAddress(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userid").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	addressID, err := c.ParamsInt("address_id")
+	if err != nil || addressID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid address ID"})
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var addr models.Address
+		if err := tx.Where("id = ? AND user_id = ?", addressID, userID).First(&addr).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&addr).Error; err != nil {
+			return err
+		}
+		if !addr.IsDefault {
+			return nil
+		}
+		var next models.Address
+		err := tx.Where("user_id = ?", userID).Order("updated_at DESC").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&next).Update("is_default", true).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Address not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete address"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
+		Message: "Address deleted",
+	})
+}
+
+func loadAddresses(userID uuid.UUID) ([]models.AddressResponse, error) {
+	var addresses []models.Address
+	if err := db.DB.Where("user_id = ?", userID).Order("is_default DESC, updated_at DESC").Find(&addresses).Error; err != nil {
+		return nil, err
+	}
+	resp := make([]models.AddressResponse, len(addresses))
+	for i := range addresses {
+		resp[i] = addresses[i].ToResponse()
+	}
+	return resp, nil
+}
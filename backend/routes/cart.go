@@ -3,7 +3,11 @@ package routes
 import (
 	"Bakery_Pos/db"
 	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+	"errors"
+	"math"
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
@@ -28,7 +32,7 @@ func GetCart(c *fiber.Ctx) error {
 	var cart models.Cart
 	err = db.DB.
 		Preload("Items.Product.Promotions").
-		Preload("Items.Product.Images").
+		Preload("Items.Product.Images", models.ImagesByPosition).
 		Where("user_id = ?", userID).
 		First(&cart).Error
 
@@ -43,7 +47,11 @@ func GetCart(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(cart.ToResponse())
+	tier, err := module.UserTier(db.DB, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+	return c.Status(fiber.StatusOK).JSON(cart.ToResponseFor(module.TierPricingFor(tier)))
 }
 
 // DeleteCart godoc
@@ -109,12 +117,17 @@ func UpdateProductCart(c *fiber.Ctx) error {
 	if fastMode {
 		cartQuery = cartQuery.Preload("Items")
 	} else {
-		cartQuery = cartQuery.Preload("Items.Product.Images").Preload("Items.Product.Promotions")
+		cartQuery = cartQuery.Preload("Items.Product.Images", models.ImagesByPosition).Preload("Items.Product.Promotions")
 	}
 	if err := cartQuery.FirstOrCreate(&cart, models.Cart{UserID: userID}).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to load or create cart"})
 	}
 
+	tier, err := module.UserTier(db.DB, userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Database error"})
+	}
+
 	var cartItem models.CartItem
 	err = db.DB.Where("cart_id = ? AND product_id = ?", cart.ID, productIDUint).First(&cartItem).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
@@ -125,6 +138,13 @@ func UpdateProductCart(c *fiber.Ctx) error {
 		if body.Quantity <= 0 {
 			return c.Status(400).JSON(fiber.Map{"error": "Item does not exist"})
 		}
+		var product models.Product
+		if err := db.DB.Select("id", "early_access_until").First(&product, productIDUint).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{"error": "Product not found"})
+		}
+		if product.EarlyAccess(time.Now()) && (tier == nil || !tier.EarlyAccess) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "This product is in early access for members"})
+		}
 		cartItem = models.CartItem{
 			CartID:    cart.ID,
 			ProductID: uint(productIDUint),
@@ -157,7 +177,7 @@ func UpdateProductCart(c *fiber.Ctx) error {
 	if cartItem.Product == nil || cartItem.Product.ID == 0 {
 		productQuery := db.DB.Where("id = ?", cartItem.ProductID)
 		if !fastMode {
-			productQuery = productQuery.Preload("Images").Preload("Promotions")
+			productQuery = productQuery.Preload("Images", models.ImagesByPosition).Preload("Promotions")
 		}
 		if err := productQuery.First(&cartItem.Product).Error; err != nil {
 			return c.Status(500).JSON(fiber.Map{"error": "Failed to load product info"})
@@ -168,14 +188,16 @@ func UpdateProductCart(c *fiber.Ctx) error {
 		Items: []models.CartItem{cartItem},
 	}
 
-	return c.Status(200).JSON(cartToReturn.ToResponse())
+	return c.Status(200).JSON(cartToReturn.ToResponseFor(module.TierPricingFor(tier)))
 }
 
 // Checkout godoc
 // @Summary Checkout cart
-// @Description Convert user's cart to an order
+// @Description Convert user's cart to an order. The chosen address, or else the default one, is copied onto the order. redeem_points takes loyalty points off the total, up to the redeem cap. Items are priced with the member's tier discount and delivery is free for tiers that include it. store_credit and gift_cards pay part or all of the total; amount_due is left to pay by transfer.
 // @Tags Cart
+// @Accept json
 // @Produce json
+// @Param request body models.BodyCheckoutRequest false "Delivery address"
 // @Success 200 {object} models.CheckoutResponse
 // @Router /cart/checkout [post]
 func Checkout(c *fiber.Ctx) error {
@@ -185,6 +207,21 @@ func Checkout(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
 	}
 
+	// the body is optional, older clients post nothing
+	var body models.BodyCheckoutRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+		}
+	}
+	shipping, err := module.ShippingAddress(db.DB, userID, body.AddressID)
+	if errors.Is(err, module.ErrAddressNotFound) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Address not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load address"})
+	}
+
 	var cart models.Cart
 	if err := db.DB.Preload("Items.Product.Promotions").Where("user_id = ?", userID).First(&cart).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Cart not found"})
@@ -194,16 +231,63 @@ func Checkout(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cart is empty"})
 	}
 
-	var total float64
+	tier, err := module.UserTier(db.DB, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+	pricing := module.TierPricingFor(tier)
+
+	now := time.Now()
+	var subtotal, listTotal float64
 	for _, item := range cart.Items {
-		price := item.Product.FinalPrice()
-		total += float64(item.Quantity) * price
+		if item.Product.EarlyAccess(now) && (tier == nil || !tier.EarlyAccess) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": item.Product.Name + " is in early access for members"})
+		}
+		price := item.Product.MemberPrice(pricing)
+		subtotal += float64(item.Quantity) * price
+		listTotal += float64(item.Quantity) * item.Product.Price
 	}
 
+	if body.RedeemPoints < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "redeem_points cannot be negative"})
+	}
+	if body.StoreCredit < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "store_credit cannot be negative"})
+	}
+	giftCards, err := normalizeGiftCards(body.GiftCards)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid gift card code"})
+	}
+	loyalty := module.LoyaltyConfig()
+	var discount float64
+	if body.RedeemPoints > 0 {
+		balance, err := module.LoyaltyBalance(db.DB, userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load points"})
+		}
+		if limit := module.MaxRedeemable(loyalty, subtotal, balance); body.RedeemPoints > limit {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "You can redeem at most " + strconv.Itoa(limit) + " points on this order",
+			})
+		}
+		discount = math.Round(float64(body.RedeemPoints)*loyalty.PointValue*100) / 100
+	}
+	var deliveryFee float64
+	if shipping.Recipient != "" {
+		deliveryFee = module.DeliveryFee(tier)
+	}
+	total := subtotal - discount + deliveryFee
+
 	order := models.Order{
-		UserID: userID,
-		Total:  total,
-		Status: "pending",
+		UserID:         &userID,
+		Subtotal:       subtotal,
+		PointsRedeemed: body.RedeemPoints,
+		PointsDiscount: discount,
+		DeliveryFee:    deliveryFee,
+		ItemDiscount:   math.Round((listTotal-subtotal)*100) / 100,
+		Total:          total,
+		Status:         "pending",
+		Shipping:       shipping,
 	}
 
 	tx := db.DB.Begin()
@@ -211,9 +295,26 @@ func Checkout(c *fiber.Ctx) error {
 		tx.Rollback()
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to create order"})
 	}
+	if order.PointsRedeemed > 0 {
+		// the balance is checked again under a lock, it may have changed
+		if err := module.RedeemPoints(tx, &order, order.PointsRedeemed); err != nil {
+			tx.Rollback()
+			if errors.Is(err, module.ErrInsufficientPoints) {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Not enough points"})
+			}
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to redeem points"})
+		}
+	}
+	if err := module.PayWithTenders(tx, &order, body.StoreCredit, giftCards); err != nil {
+		tx.Rollback()
+		if status, msg, ok := storedValueError(err); ok {
+			return c.Status(status).JSON(fiber.Map{"error": msg})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to take payment"})
+	}
 
 	for _, item := range cart.Items {
-		price := item.Product.FinalPrice()
+		price := item.Product.MemberPrice(pricing)
 		orderItem := models.OrderItem{
 			OrderID:   order.ID,
 			ProductID: item.ProductID,
@@ -239,10 +340,20 @@ func Checkout(c *fiber.Ctx) error {
 	tx.Commit()
 
 	res := models.CheckoutResponse{
-		Message: "Checkout successful",
-		OrderID: order.ID,
-		Total:   total,
-		Status:  order.Status,
+		Message:        "Checkout successful",
+		OrderID:        order.ID,
+		Subtotal:       subtotal,
+		PointsRedeemed: order.PointsRedeemed,
+		PointsDiscount: discount,
+		DeliveryFee:    deliveryFee,
+		Total:          total,
+		Status:         order.Status,
+
+		Payments:  order.Payments,
+		AmountDue: math.Round((total-order.TenderTotal)*100) / 100,
+	}
+	if res.Payments == nil {
+		res.Payments = []models.OrderPayment{}
 	}
 
 	return c.Status(200).JSON(res)
@@ -0,0 +1,109 @@
+package routes
+
+import (
+	"errors"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// GetGiftCardBalance godoc
+// @Summary Check a gift card balance
+// @Description Spaces and dashes in the code are ignored
+// @Tags gift-card
+// @Produce json
+// @Param code path string true "Gift card code"
+// @Success 200 {object} models.GiftCardBalanceResponse
+// @Router /user/gift-cards/{code} [get]
+// @Security BearerAuth
+func GetGiftCardBalance(c *fiber.Ctx) error {
+	code, err := module.NormalizeGiftCardCode(c.Params("code"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid gift card code"})
+	}
+	card, err := module.FindGiftCard(db.DB, code)
+	if errors.Is(err, module.ErrGiftCardNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Gift card not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch gift card"})
+	}
+	return c.Status(fiber.StatusOK).JSON(models.GiftCardBalanceResponse{
+		Code:      module.MaskGiftCardCode(card.Code),
+		Balance:   card.Balance,
+		Status:    card.Status,
+		ExpiresAt: card.ExpiresAt,
+	})
+}
+
+// GetStoreCredit godoc
+// @Summary My store credit
+// @Description Balance and wallet history, newest first
+// @Tags gift-card
+// @Produce json
+// @Param limit query int false "Page size (default 50)"
+// @Param page query int false "Page number (default 1)"
+// @Success 200 {object} models.StoreCreditResponse
+// @Router /user/store-credit [get]
+// @Security BearerAuth
+func GetStoreCredit(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userid").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	limit := c.QueryInt("limit", 50)
+	page := c.QueryInt("page", 1)
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	resp, err := module.StoreCreditSummary(db.DB, userID, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch store credit"})
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// normalizeGiftCards validates the codes and drops repeats.
+func normalizeGiftCards(codes []string) ([]string, error) {
+	out := make([]string, 0, len(codes))
+	seen := make(map[string]bool, len(codes))
+	for _, raw := range codes {
+		code, err := module.NormalizeGiftCardCode(raw)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[code] {
+			seen[code] = true
+			out = append(out, code)
+		}
+	}
+	return out, nil
+}
+
+// storedValueError maps a gift card or store credit error to a response.
+func storedValueError(err error) (int, string, bool) {
+	switch {
+	case errors.Is(err, module.ErrInsufficientCredit):
+		return fiber.StatusConflict, "Not enough store credit", true
+	case errors.Is(err, module.ErrGiftCardNotFound):
+		return fiber.StatusNotFound, "Gift card not found", true
+	case errors.Is(err, module.ErrGiftCardInactive):
+		return fiber.StatusConflict, "Gift card has not been activated", true
+	case errors.Is(err, module.ErrGiftCardVoid):
+		return fiber.StatusConflict, "Gift card has been voided", true
+	case errors.Is(err, module.ErrGiftCardExpired):
+		return fiber.StatusConflict, "Gift card has expired", true
+	case errors.Is(err, module.ErrGiftCardEmpty):
+		return fiber.StatusConflict, "Gift card has no balance left", true
+	}
+	return 0, "", false
+}
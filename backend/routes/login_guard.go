@@ -0,0 +1,90 @@
+package routes
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/middleware"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type loginAttemptKey struct {
+	key    string
+	policy module.LoginPolicy
+}
+
+// loginAttemptKeys are the counters a login is checked against.
+func loginAttemptKeys(c *fiber.Ctx, username string) []loginAttemptKey {
+	return []loginAttemptKey{
+		{module.UserAttemptKey(username), module.UserLoginPolicy},
+		{module.IPAttemptKey(c.IP()), module.IPLoginPolicy},
+	}
+}
+
+// throttleLogin answers 429 with Retry-After when the username or the IP is
+// locked or still inside its progressive delay. blocked reports whether a
+// response was written. Store errors fail open so an outage of the store
+// cannot lock everyone out.
+func throttleLogin(c *fiber.Ctx, username string) (blocked bool, err error) {
+	for _, check := range loginAttemptKeys(c, username) {
+		wait, locked, err := module.CheckAttempt(c.UserContext(), db.Attempts, check.key, check.policy)
+		if err != nil {
+			log.Printf("Login attempt check failed for %s: %v", check.key, err)
+			continue
+		}
+		if wait <= 0 {
+			continue
+		}
+
+		seconds := int(math.Ceil(wait.Seconds()))
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+		msg := fmt.Sprintf("Too many failed attempts, try again in %d seconds", seconds)
+		if locked {
+			msg = fmt.Sprintf("Too many failed attempts, locked for %d minutes", int(math.Ceil(wait.Minutes())))
+		}
+		return true, c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": msg})
+	}
+	return false, nil
+}
+
+// recordLoginFailure counts a failed login for the username and the IP and
+// writes a security.lockout audit entry when either gets locked. userID is
+// empty for unknown usernames, which are counted all the same.
+func recordLoginFailure(c *fiber.Ctx, username, userID string) {
+	ctx := c.UserContext()
+	for _, check := range loginAttemptKeys(c, username) {
+		state, locked, err := module.RecordFailure(ctx, db.Attempts, check.key, check.policy)
+		if err != nil {
+			log.Printf("Failed to record login attempt for %s: %v", check.key, err)
+			continue
+		}
+		if !locked {
+			continue
+		}
+
+		entry := module.NewAuditLog("", "security.lockout", "login", check.key, nil, fiber.Map{
+			"user_id":      userID,
+			"failures":     state.Failures,
+			"locked_until": state.LockedUntil.Format(time.RFC3339),
+		})
+		middleware.AuditRequest(c, &entry)
+		if err := module.RecordAudit(db.DB, entry); err != nil {
+			log.Printf("Failed to record lockout of %s: %v", check.key, err)
+		}
+	}
+}
+
+// resetLoginFailures clears the username counter after a successful login.
+// The IP counter is left to expire, otherwise logging into one's own account
+// would reset an attack on others from the same address.
+func resetLoginFailures(c *fiber.Ctx, username string) {
+	if err := db.Attempts.Reset(c.UserContext(), module.UserAttemptKey(username)); err != nil {
+		log.Printf("Failed to reset login attempts for %s: %v", username, err)
+	}
+}
@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"Bakery_Pos/db"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// GetLoyalty godoc
+// @Summary My loyalty points
+// @Description Balance, points expiring in the next 30 days and the points history, newest first
+// @Tags user
+// @Produce json
+// @Param limit query int false "Page size (default 50)"
+// @Param page query int false "Page number (default 1)"
+// @Success 200 {object} models.LoyaltyResponse
+// @Router /user/loyalty [get]
+// @Security BearerAuth
+func GetLoyalty(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userid").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	limit := c.QueryInt("limit", 50)
+	page := c.QueryInt("page", 1)
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	resp, err := module.LoyaltySummary(db.DB, userID, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch points"})
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
@@ -0,0 +1,280 @@
+package routes
+
+import (
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/middleware"
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// mfaChallenge answers the password step of a login for a user with 2FA.
+func mfaChallenge(c *fiber.Ctx, user *models.User) error {
+	exp := time.Now().Add(module.MFATokenTTL)
+	token, err := module.GenerateMFAToken(user, exp)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate token",
+		})
+	}
+	return c.Status(fiber.StatusAccepted).JSON(models.LoginMFAResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresAt:   exp,
+	})
+}
+
+// LoginMFAHandler godoc
+// @Summary Complete a login with a two-factor code
+// @Description Takes the mfa_token from /user/login and a TOTP or backup code, and starts the session
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body models.BodyLoginMFARequest true "MFA token and code"
+// @Success 200 {object} models.UserResponse
+// @Router /user/login/mfa [post]
+func LoginMFAHandler(c *fiber.Ctx) error {
+	var body models.BodyLoginMFARequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	userID, version, err := module.ParseMFAToken(body.MFAToken)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Login expired, please log in again"})
+	}
+
+	var user models.User
+	if err := db.DB.Where("id = ?", userID).First(&user).Error; err != nil || user.TokenVersion != version || user.MFAEnabledAt == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Login expired, please log in again"})
+	}
+	if user.DisabledAt != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Account is disabled"})
+	}
+
+	if blocked, err := throttleLogin(c, user.Username); blocked {
+		return err
+	}
+	if err := module.VerifyMFA(db.DB, &user, body.Code); err != nil {
+		if errors.Is(err, module.ErrMFAInvalid) {
+			recordLoginFailure(c, user.Username, user.ID.String())
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid two-factor code"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify code"})
+	}
+	resetLoginFailures(c, user.Username)
+
+	resp, err := startSession(c, &user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// EnrollMFA godoc
+// @Summary Start two-factor enrollment
+// @Description Returns a new TOTP secret as text, otpauth URI and QR code. 2FA is enabled once a code is verified with /user/mfa/verify.
+// @Tags user
+// @Produce json
+// @Success 200 {object} models.MFAEnrollResponse
+// @Router /user/mfa/enroll [post]
+// @Security BearerAuth
+func EnrollMFA(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if user.MFAEnabledAt != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+	}
+
+	secret, err := module.GenerateTOTPSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate secret"})
+	}
+	sealed, err := module.SealMFASecret(secret)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to store secret"})
+	}
+	if err := db.DB.Model(&user).Updates(map[string]any{"mfa_secret": sealed, "mfa_last_step": 0}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to store secret"})
+	}
+
+	uri := module.OTPAuthURI(module.MFAIssuer(), user.Username, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to render QR code"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.MFAEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: uri,
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	})
+}
+
+// VerifyMFA godoc
+// @Summary Confirm two-factor enrollment
+// @Description Enables 2FA when the code matches the enrolled secret and returns single use backup codes, shown only once.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body models.BodyMFACodeRequest true "TOTP code"
+// @Success 200 {object} models.MFABackupCodesResponse
+// @Router /user/mfa/verify [post]
+// @Security BearerAuth
+func VerifyMFA(c *fiber.Ctx) error {
+	var body models.BodyMFACodeRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if user.MFAEnabledAt != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+	}
+	if user.MFASecret == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Start enrollment first"})
+	}
+
+	var codes []string
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := module.VerifyMFA(tx, &user, body.Code); err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Update("mfa_enabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+		var err error
+		if codes, err = module.ReplaceBackupCodes(tx, user.ID); err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(user.ID.String(), "user.mfa_enable", "user", user.ID.String(), nil, nil)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if errors.Is(err, module.ErrMFAInvalid) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid two-factor code"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to enable two-factor authentication"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.MFABackupCodesResponse{BackupCodes: codes})
+}
+
+// DisableMFA godoc
+// @Summary Turn off two-factor authentication
+// @Description Requires the password and a TOTP or backup code
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body models.BodyMFADisableRequest true "Password and code"
+// @Success 200 {object} models.MessageResponse
+// @Router /user/mfa/disable [post]
+// @Security BearerAuth
+func DisableMFA(c *fiber.Ctx) error {
+	var body models.BodyMFADisableRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if user.MFAEnabledAt == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Two-factor authentication is not enabled"})
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.Password)); err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Password is incorrect"})
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := module.VerifyMFA(tx, &user, body.Code); err != nil {
+			return err
+		}
+		if err := module.ClearMFA(tx, user.ID); err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(user.ID.String(), "user.mfa_disable", "user", user.ID.String(), nil, nil)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if errors.Is(err, module.ErrMFAInvalid) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid two-factor code"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to disable two-factor authentication"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
+		Message: "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateBackupCodes godoc
+// @Summary Replace the backup codes
+// @Description Requires a current TOTP code; backup codes are not accepted. Old backup codes stop working.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body models.BodyMFACodeRequest true "TOTP code"
+// @Success 200 {object} models.MFABackupCodesResponse
+// @Router /user/mfa/backup-codes [post]
+// @Security BearerAuth
+func RegenerateBackupCodes(c *fiber.Ctx) error {
+	var body models.BodyMFACodeRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if user.MFAEnabledAt == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Two-factor authentication is not enabled"})
+	}
+
+	var codes []string
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := module.VerifyTOTP(tx, &user, body.Code); err != nil {
+			return err
+		}
+		var err error
+		codes, err = module.ReplaceBackupCodes(tx, user.ID)
+		return err
+	})
+	if errors.Is(err, module.ErrMFAInvalid) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid two-factor code"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate backup codes"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.MFABackupCodesResponse{BackupCodes: codes})
+}
+
+// currentUser loads the authenticated user.
+func currentUser(c *fiber.Ctx) (models.User, error) {
+	var user models.User
+	userID, err := uuid.Parse(c.Locals("userid").(string))
+	if err != nil {
+		return user, err
+	}
+	err = db.DB.Where("id = ?", userID).First(&user).Error
+	return user, err
+}
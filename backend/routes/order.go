@@ -1,42 +1,62 @@
 package routes
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"time"
 
 	"Bakery_Pos/db"
+	"Bakery_Pos/middleware"
 	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+	"Bakery_Pos/storage"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // GetAllOrders godoc
 // @Summary Get all orders for the current user
-// @Description Retrieve all orders of the logged-in user
+// @Description Retrieve all orders of the logged-in user. Get a single order for its slip_url. An API key with orders.read lists every order instead, newest first, a page at a time.
 // @Tags Order
 // @Produce json
+// @Param limit query int false "Page size for API keys (default 50)"
+// @Param page query int false "Page number for API keys (default 1)"
 // @Success 200 {array} models.OrderResponse
 // @Router /order [get]
 func GetAllOrders(c *fiber.Ctx) error {
-	userIDStr := c.Locals("userid").(string)
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	query := db.DB.Preload("Items").Preload("Payments")
+	if middleware.IsAPIKey(c) {
+		if !middleware.HasPermission(c, models.PermOrdersRead) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied: missing permission " + models.PermOrdersRead})
+		}
+		limit := c.QueryInt("limit", 50)
+		page := c.QueryInt("page", 1)
+		if limit <= 0 || limit > 500 {
+			limit = 50
+		}
+		if page < 1 {
+			page = 1
+		}
+		query = query.Order("created_at DESC, id DESC").Limit(limit).Offset((page - 1) * limit)
+	} else {
+		userID, err := uuid.Parse(c.Locals("userid").(string))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+		}
+		query = query.Where("user_id = ?", userID)
 	}
 
 	var orders []models.Order
-	if err := db.DB.Preload("Items").Where("user_id = ?", userID).Find(&orders).Error; err != nil {
+	if err := query.Find(&orders).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch orders"})
 	}
 
 	var resp []models.OrderResponse
 	for _, order := range orders {
-		temp := order.ToResponse()
-		filePath := fmt.Sprintf("orders/%s/%s", order.ID, "slip.png")
-		public_url := db.Storage.GetPublicURL("order-slips", filePath)
-		temp.PublicURL = &public_url
-		resp = append(resp, temp)
+		resp = append(resp, order.ToResponse())
 	}
 
 	return c.Status(fiber.StatusOK).JSON(resp)
@@ -44,45 +64,66 @@ func GetAllOrders(c *fiber.Ctx) error {
 
 // GetOrderByID godoc
 // @Summary Get a single order by ID
-// @Description Retrieve a single order of the logged-in user
+// @Description Retrieve a single order of the logged-in user. slip_url is a signed link valid for 10 minutes, set once a slip has been uploaded. An API key with orders.read can read any order, without upload_url.
 // @Tags Order
 // @Produce json
 // @Param order_id path string true "Order ID"
 // @Success 200 {object} models.OrderResponse
 // @Router /order/{order_id} [get]
 func GetOrderByID(c *fiber.Ctx) error {
-	userIDStr := c.Locals("userid").(string)
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	query := db.DB.Preload("Items").Preload("Payments").Where("id = ?", c.Params("order_id"))
+	apiKey := middleware.IsAPIKey(c)
+	if apiKey {
+		if !middleware.HasPermission(c, models.PermOrdersRead) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied: missing permission " + models.PermOrdersRead})
+		}
+	} else {
+		userID, err := uuid.Parse(c.Locals("userid").(string))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+		}
+		query = query.Where("user_id = ?", userID)
 	}
 
-	orderID := c.Params("order_id")
 	var order models.Order
-	if err := db.DB.Preload("Items").Where("id = ? AND user_id = ?", orderID, userID).First(&order).Error; err != nil {
+	if err := query.First(&order).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch order"})
 	}
 
-	filePath := fmt.Sprintf("orders/%s/%s", order.ID, "slip.png")
+	if apiKey {
+		resp := order.ToResponse()
+		resp.SlipURL = signedSlipURL(c.UserContext(), order.ID)
+		return c.Status(fiber.StatusOK).JSON(resp)
+	}
 
-	signedURL, publicURL, err := db.Storage.GenerateUploadURL("order-slips", filePath)
+	signedURL, _, err := db.Storage.GenerateUploadURL(c.UserContext(), module.SlipBucket, module.SlipPath(order.ID))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(storage.HTTPStatus(err)).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	resp := order.ToResponse()
 	resp.UploadURL = &signedURL
-	resp.PublicURL = &publicURL
+	resp.SlipURL = signedSlipURL(c.UserContext(), order.ID)
 
 	return c.Status(fiber.StatusOK).JSON(resp)
 }
 
 var orderStatusSteps = []string{"pending", "confirmed", "shipping", "delivered"}
 
+// orderCancelled is outside the steps: any order can be cancelled and a
+// cancelled order cannot change again.
+const orderCancelled = "cancelled"
+
 func isValidStatusTransition(current, next string) bool {
+	if current == orderCancelled {
+		return false
+	}
+	if next == orderCancelled {
+		return true
+	}
 	currentIndex := -1
 	nextIndex := -1
 	for i, s := range orderStatusSteps {
@@ -102,9 +143,22 @@ func isValidStatusTransition(current, next string) bool {
 	return true
 }
 
+// statusReached reports whether status is step or a later one.
+func statusReached(status, step string) bool {
+	for _, s := range orderStatusSteps {
+		if s == step {
+			return true
+		}
+		if s == status {
+			return false
+		}
+	}
+	return false
+}
+
 // UpdateOrderStatus godoc
 // @Summary Update the status of an order
-// @Description Update the status of a single order for the logged-in user
+// @Description Update the status of a single order. Loyalty points are earned when the order reaches the earn status and given back or taken back when it is cancelled, along with any gift card and store credit payments.
 // @Tags Order
 // @Accept json
 // @Produce json
@@ -119,23 +173,59 @@ func UpdateOrderStatus(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
+	errPOSOrder := errors.New("order is a counter sale")
+	errSkippedStep := errors.New("status transition skips a step")
 	var order models.Order
-	if err := db.DB.Where("id = ?", orderID).First(&order).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// locked so two updates can't both pass the transition check and
+		// earn or refund twice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", orderID).First(&order).Error; err != nil {
+			return err
+		}
+		if order.Channel == models.ChannelPOS {
+			return errPOSOrder
+		}
+		if !isValidStatusTransition(order.Status, body.Status) {
+			return errSkippedStep
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch order"})
-	}
 
-	if !isValidStatusTransition(order.Status, body.Status) {
+		// อัพเดต status
+		order.Status = body.Status
+		if err := tx.Model(&order).Update("status", order.Status).Error; err != nil {
+			return err
+		}
+		if order.Status == orderCancelled {
+			if err := module.ReverseOrderPoints(tx, &order); err != nil {
+				return err
+			}
+			if err := module.RefundOrderPayments(tx, &order); err != nil {
+				return err
+			}
+		} else if statusReached(order.Status, module.LoyaltyConfig().EarnStatus) {
+			if _, err := module.EarnPoints(tx, &order); err != nil {
+				return err
+			}
+		}
+		if order.UserID == nil {
+			return nil
+		}
+		// upgrade (or drop) the customer's tier right away instead of waiting for the nightly run
+		_, err := module.RecalculateTiers(tx, order.UserID, time.Now())
+		return err
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
+	case errors.Is(err, errPOSOrder):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Counter sales are voided from the POS",
+		})
+	case errors.Is(err, errSkippedStep):
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Cannot skip status steps",
 		})
-	}
-
-	// อัพเดต status
-	order.Status = body.Status
-	if err := db.DB.Save(&order).Error; err != nil {
+	case err != nil:
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update order status"})
 	}
 
@@ -165,16 +255,13 @@ func GenerateOrderSlipURL(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
 	}
 
-	filePath := fmt.Sprintf("orders/%s/%s", order.ID, "slip.png")
-
-	signedURL, publicURL, err := db.Storage.GenerateUploadURL("order-slips", filePath)
+	signedURL, _, err := db.Storage.GenerateUploadURL(c.UserContext(), module.SlipBucket, module.SlipPath(order.ID))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(storage.HTTPStatus(err)).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	resp := models.UploadOrderSlipResponse{
 		UploadURL: signedURL,
-		PublicURL: publicURL,
 	}
 
 	return c.Status(fiber.StatusOK).JSON(resp)
@@ -182,7 +269,7 @@ func GenerateOrderSlipURL(c *fiber.Ctx) error {
 
 // DeleteOrder godoc
 // @Summary Delete an order
-// @Description Delete a single order of the logged-in user
+// @Description Delete a pending order of the logged-in user. Points, gift card and store credit payments are given back first. Orders past pending are kept for the shop's accounts and answer 409; they are cancelled instead.
 // @Tags Order
 // @Produce json
 // @Param order_id path string true "Order ID"
@@ -196,7 +283,32 @@ func DeleteOrder(c *fiber.Ctx) error {
 	}
 
 	orderID := c.Params("order_id")
-	if err := db.DB.Where("id = ? AND user_id = ?", orderID, userID).Delete(&models.Order{}).Error; err != nil {
+	errNotPending := errors.New("order is past pending")
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ? AND channel = ?", orderID, userID, models.ChannelOnline).
+			First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if order.Status != "pending" {
+			return errNotPending
+		}
+		if err := module.ReverseOrderPoints(tx, &order); err != nil {
+			return err
+		}
+		if err := module.RefundOrderPayments(tx, &order); err != nil {
+			return err
+		}
+		return tx.Delete(&order).Error
+	})
+	if errors.Is(err, errNotPending) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Only pending orders can be deleted"})
+	}
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete order"})
 	}
 
@@ -204,3 +316,13 @@ func DeleteOrder(c *fiber.Ctx) error {
 		Message: "Order deleted successfully",
 	})
 }
+
+// signedSlipURL returns a short-lived download URL for the order's slip, or nil
+// when none has been uploaded yet.
+func signedSlipURL(ctx context.Context, orderID string) *string {
+	url, err := module.SignSlipURL(ctx, db.Storage, orderID)
+	if err != nil {
+		return nil
+	}
+	return &url
+}
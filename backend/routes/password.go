@@ -0,0 +1,199 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/middleware"
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+	"Bakery_Pos/notify"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Requires the current password. Every session is revoked and the caller gets a fresh one.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body models.BodyChangePasswordRequest true "Current and new password"
+// @Success 200 {object} models.UserResponse
+// @Router /user/password/change [post]
+// @Security BearerAuth
+func ChangePassword(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userid").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	var body models.BodyChangePasswordRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	var user models.User
+	if err := db.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.CurrentPassword)); err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Current password is incorrect"})
+	}
+	if err := module.ValidatePassword(body.NewPassword, user.Username); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		return setPassword(tx, c, &user, body.NewPassword, "user.password_change")
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to change password"})
+	}
+
+	resp, err := startSession(c, &user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// RequestPasswordReset godoc
+// @Summary Request a password reset
+// @Description Sends a single use reset token through the configured notifier. Always answers 200 so usernames cannot be probed.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body models.BodyPasswordResetRequest true "Username"
+// @Success 200 {object} models.MessageResponse
+// @Router /user/password/reset [post]
+func RequestPasswordReset(c *fiber.Ctx) error {
+	var body models.BodyPasswordResetRequest
+	if err := c.BodyParser(&body); err != nil || strings.TrimSpace(body.Username) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Username is required"})
+	}
+
+	resp := models.MessageResponse{
+		Message: "If the account exists, reset instructions have been sent",
+	}
+
+	var user models.User
+	if err := db.DB.Where("username = ?", strings.TrimSpace(body.Username)).First(&user).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Password reset lookup failed: %v", err)
+		}
+		return c.Status(fiber.StatusOK).JSON(resp)
+	}
+	if user.DisabledAt != nil {
+		return c.Status(fiber.StatusOK).JSON(resp)
+	}
+
+	var token string
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if token, err = module.CreatePasswordResetToken(tx, user.ID, c.IP()); err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(user.ID.String(), "user.password_reset_request", "user", user.ID.String(), nil, nil)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create reset token"})
+	}
+
+	to := user.Username
+	if user.PhoneNumber != nil {
+		to = *user.PhoneNumber
+	}
+	msg := notify.Message{
+		To:      to,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Use this link within %d minutes to set a new password:\n%s/reset-password?token=%s\n\nIf you did not ask for this, ignore this message.",
+			int(module.PasswordResetTTL.Minutes()), frontendURL(), token),
+	}
+	if err := notify.Default.Send(c.UserContext(), msg); err != nil {
+		log.Printf("Failed to send password reset for %s: %v", user.ID, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// ConfirmPasswordReset godoc
+// @Summary Set a new password with a reset token
+// @Description The token can be used once. All sessions of the user are revoked.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body models.BodyPasswordResetConfirm true "Token and new password"
+// @Success 200 {object} models.MessageResponse
+// @Router /user/password/reset/confirm [post]
+func ConfirmPasswordReset(c *fiber.Ctx) error {
+	var body models.BodyPasswordResetConfirm
+	if err := c.BodyParser(&body); err != nil || body.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		userID, err := module.ConsumePasswordResetToken(tx, body.Token)
+		if err != nil {
+			return err
+		}
+		var user models.User
+		if err := tx.Where("id = ?", userID).First(&user).Error; err != nil {
+			return module.ErrResetTokenInvalid
+		}
+		if err := module.ValidatePassword(body.NewPassword, user.Username); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return setPassword(tx, c, &user, body.NewPassword, "user.password_reset")
+	})
+	var fiberErr *fiber.Error
+	switch {
+	case errors.Is(err, module.ErrResetTokenInvalid):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Reset link is invalid or expired"})
+	case errors.As(err, &fiberErr):
+		return c.Status(fiberErr.Code).JSON(fiber.Map{"error": fiberErr.Message})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset password"})
+	}
+
+	clearAuthCookies(c)
+	return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
+		Message: "Password has been reset, please log in",
+	})
+}
+
+// setPassword stores the new password hash, revokes every session and writes
+// the audit entry.
+func setPassword(tx *gorm.DB, c *fiber.Ctx, user *models.User, password, action string) error {
+	hash, err := module.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(user).Update("password", hash).Error; err != nil {
+		return err
+	}
+	if err := module.RevokeAllSessions(tx, user.ID); err != nil {
+		return err
+	}
+	user.TokenVersion++
+	entry := module.NewAuditLog(user.ID.String(), action, "user", user.ID.String(), nil, nil)
+	middleware.AuditRequest(c, &entry)
+	return module.RecordAudit(tx, entry)
+}
+
+// frontendURL is where links in notifications point, from FRONTEND_URL.
+func frontendURL() string {
+	if v := os.Getenv("FRONTEND_URL"); v != "" {
+		return strings.TrimRight(v, "/")
+	}
+	return "http://localhost:3000"
+}
@@ -0,0 +1,457 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/middleware"
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errCustomerNotFound  = errors.New("no customer with that phone number")
+	errCustomerAmbiguous = errors.New("several customers share that phone number")
+	errOutOfStock        = errors.New("not enough stock")
+	errCashShort         = errors.New("cash received is less than the amount due")
+	errNotCounterSale    = errors.New("order is not a counter sale")
+	errAlreadyVoided     = errors.New("sale is already void")
+	errVoidNotAllowed    = errors.New("only the shift's cashier or a manager may void")
+)
+
+// GetPOSCustomer godoc
+// @Summary Find a customer by phone number
+// @Description For attaching a member to a counter sale. Spaces, dashes and a +66 prefix are ignored.
+// @Tags pos
+// @Produce json
+// @Param phone query string true "Phone number"
+// @Success 200 {object} models.POSCustomerResponse
+// @Router /pos/customers [get]
+// @Security BearerAuth
+func GetPOSCustomer(c *fiber.Ctx) error {
+	user, err := customerByPhone(c.Query("phone"))
+	switch {
+	case errors.Is(err, errCustomerNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Customer not found"})
+	case errors.Is(err, errCustomerAmbiguous):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Several customers share this phone number"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to look up customer"})
+	}
+
+	resp := models.POSCustomerResponse{
+		UserID:      user.ID,
+		Name:        displayName(&user),
+		PhoneNumber: *user.PhoneNumber,
+	}
+	if user.Tier != nil {
+		resp.Tier = user.Tier.Name
+	}
+	if resp.Points, err = module.LoyaltyBalance(db.DB, user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load points"})
+	}
+	if resp.StoreCredit, err = module.StoreCreditBalance(db.DB, user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load store credit"})
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// CreatePOSSale godoc
+// @Summary Ring up a counter sale
+// @Description Creates a completed order with channel pos from the given items, without a cart, on the cashier's open shift. A customer found by phone number gets tier pricing and earns points. Stock is taken off, except for products sold by weight. Store credit and gift cards pay first and cash or card the rest.
+// @Tags pos
+// @Accept json
+// @Produce json
+// @Param request body models.BodyPOSSaleRequest true "Items and payment"
+// @Success 201 {object} models.ReceiptResponse
+// @Router /pos/sales [post]
+// @Security BearerAuth
+func CreatePOSSale(c *fiber.Ctx) error {
+	var body models.BodyPOSSaleRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	if len(body.Items) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A sale needs at least one item"})
+	}
+	switch body.PaymentMethod {
+	case "":
+		body.PaymentMethod = models.TenderCash
+	case models.TenderCash, models.TenderCard:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payment_method must be cash or card"})
+	}
+	if body.CashReceived < 0 || body.StoreCredit < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Amounts cannot be negative"})
+	}
+	giftCards, err := normalizeGiftCards(body.GiftCards)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid gift card code"})
+	}
+
+	// every sale belongs to the cashier's open shift
+	cashierID, err := uuid.Parse(c.Locals("userid").(string))
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Counter sales need a cashier account"})
+	}
+	shift, err := module.CurrentShift(db.DB, cashierID)
+	if errors.Is(err, module.ErrNoOpenShift) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Open a shift first"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load shift"})
+	}
+
+	receipt := models.ReceiptResponse{Lines: []models.ReceiptLine{}}
+	var customer *models.User
+	if strings.TrimSpace(body.CustomerPhone) != "" {
+		user, err := customerByPhone(body.CustomerPhone)
+		switch {
+		case errors.Is(err, errCustomerNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Customer not found"})
+		case errors.Is(err, errCustomerAmbiguous):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Several customers share this phone number"})
+		case err != nil:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to look up customer"})
+		}
+		customer = &user
+		receipt.Customer = displayName(customer)
+	} else if body.StoreCredit > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Store credit needs a customer"})
+	}
+	var tier *models.MembershipTier
+	if customer != nil {
+		tier = customer.Tier
+	}
+	pricing := module.TierPricingFor(tier)
+
+	// price every line before touching the database
+	now := time.Now()
+	items := make([]models.OrderItem, 0, len(body.Items))
+	stock := map[uint]int{}
+	var total, listTotal float64
+	for i, line := range body.Items {
+		if line.Quantity <= 0 {
+			line.Quantity = 1
+		}
+		query := db.DB.Preload("Promotions")
+		var product models.Product
+		var instore *module.InStoreBarcode
+		if code := strings.TrimSpace(line.Code); code != "" {
+			product, _, instore, err = productByCode(query, code)
+		} else {
+			err = query.Where("is_active").First(&product, line.ProductID).Error
+		}
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": fmt.Sprintf("Item %d: product not found", i+1)})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load products"})
+		}
+		if product.EarlyAccess(now) && (tier == nil || !tier.EarlyAccess) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": product.Name + " is in early access for members"})
+		}
+
+		item := models.OrderItem{
+			ProductID:   product.ID,
+			Quantity:    line.Quantity,
+			Name:        product.Name,
+			Description: product.Description,
+			Tag:         product.Tag,
+			Price:       product.MemberPrice(pricing),
+		}
+		listPrice := product.Price
+		// an in-store barcode is one labelled pack priced by weight or price
+		if instore != nil {
+			item.Quantity = 1
+			switch instore.Kind {
+			case module.InStoreWeight:
+				kg := float64(instore.Value) / 1000
+				item.Name = fmt.Sprintf("%s %.3f kg", product.Name, kg)
+				item.Price = math.Round(item.Price*kg*100) / 100
+				listPrice = math.Round(product.Price*kg*100) / 100
+			case module.InStorePrice:
+				item.Price = float64(instore.Value) / 100
+				listPrice = item.Price
+			}
+		}
+		modifiers := make([]string, 0, len(line.Modifiers))
+		for _, m := range line.Modifiers {
+			name := strings.TrimSpace(m.Name)
+			if name == "" || m.Price < 0 {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Item %d: modifiers need a name and a price of at least 0", i+1)})
+			}
+			if m.Price > 0 {
+				name = fmt.Sprintf("%s (+%.2f)", name, m.Price)
+				item.Price += m.Price
+				listPrice += m.Price
+			}
+			modifiers = append(modifiers, name)
+		}
+		item.Modifiers = strings.Join(modifiers, ", ")
+		item.Price = math.Round(item.Price*100) / 100
+
+		if !product.SoldByWeight {
+			stock[product.ID] += item.Quantity
+		}
+		amount := math.Round(item.Price*float64(item.Quantity)*100) / 100
+		total += amount
+		listTotal += listPrice * float64(item.Quantity)
+		items = append(items, item)
+		receipt.Lines = append(receipt.Lines, models.ReceiptLine{
+			Name:      item.Name,
+			Modifiers: item.Modifiers,
+			Quantity:  item.Quantity,
+			UnitPrice: item.Price,
+			Amount:    amount,
+		})
+	}
+	total = math.Round(total*100) / 100
+
+	order := models.Order{
+		Channel:      models.ChannelPOS,
+		Status:       "completed",
+		Subtotal:     total,
+		ItemDiscount: math.Max(0, math.Round((listTotal-total)*100)/100),
+		Total:        total,
+		CashierID:    &cashierID,
+		ShiftID:      &shift.ID,
+	}
+	if customer != nil {
+		order.UserID = &customer.ID
+	}
+	var cashier models.User
+	if db.DB.Select("id", "name", "username").Where("id = ?", cashierID).First(&cashier).Error == nil {
+		receipt.Cashier = displayName(&cashier)
+	}
+
+	var outOfStock string
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := module.LockOpenShift(tx, shift.ID); err != nil {
+			return err
+		}
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].OrderID = order.ID
+		}
+		if err := tx.Create(&items).Error; err != nil {
+			return err
+		}
+		// rows are locked in ID order so two sales of the same products can't deadlock
+		productIDs := make([]uint, 0, len(stock))
+		for productID := range stock {
+			productIDs = append(productIDs, productID)
+		}
+		slices.Sort(productIDs)
+		for _, productID := range productIDs {
+			quantity := stock[productID]
+			res := tx.Model(&models.Product{}).Where("id = ? AND stock >= ?", productID, quantity).
+				UpdateColumn("stock", gorm.Expr("stock - ?", quantity))
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				for _, item := range items {
+					if item.ProductID == productID {
+						outOfStock = item.Name
+						break
+					}
+				}
+				return errOutOfStock
+			}
+		}
+
+		if err := module.PayWithTenders(tx, &order, body.StoreCredit, giftCards); err != nil {
+			return err
+		}
+		if due := math.Round((order.Total-order.TenderTotal)*100) / 100; due > 0 {
+			if body.PaymentMethod == models.TenderCash {
+				if body.CashReceived == 0 {
+					body.CashReceived = due
+				}
+				if body.CashReceived < due {
+					return errCashShort
+				}
+				receipt.CashReceived = body.CashReceived
+				receipt.Change = math.Round((body.CashReceived-due)*100) / 100
+			}
+			payment := models.OrderPayment{OrderID: order.ID, Method: body.PaymentMethod, Amount: due}
+			if err := tx.Create(&payment).Error; err != nil {
+				return err
+			}
+			order.Payments = append(order.Payments, payment)
+			order.TenderTotal = order.Total
+			if err := tx.Model(&order).UpdateColumn("tender_total", order.TenderTotal).Error; err != nil {
+				return err
+			}
+		}
+
+		if customer == nil {
+			return nil
+		}
+		var err error
+		if receipt.PointsEarned, err = module.EarnPoints(tx, &order); err != nil {
+			return err
+		}
+		_, err = module.RecalculateTiers(tx, order.UserID, time.Now())
+		return err
+	})
+	if err != nil {
+		if status, msg, ok := storedValueError(err); ok {
+			return c.Status(status).JSON(fiber.Map{"error": msg})
+		}
+		switch {
+		case errors.Is(err, module.ErrShiftClosed):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Open a shift first"})
+		case errors.Is(err, errOutOfStock):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Not enough stock of " + outOfStock})
+		case errors.Is(err, errCashShort):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cash received is less than the amount due"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record sale"})
+	}
+
+	receipt.OrderID = order.ID
+	receipt.Status = order.Status
+	receipt.CreatedAt = order.CreatedAt
+	receipt.Total = order.Total
+	receipt.Payments = order.Payments
+	if receipt.Payments == nil {
+		receipt.Payments = []models.OrderPayment{}
+	}
+	return c.Status(fiber.StatusCreated).JSON(receipt)
+}
+
+// VoidPOSSale godoc
+// @Summary Void a counter sale
+// @Description Cancels a sale while its shift is open: tenders are given back, so its cash leaves the expected drawer total, points are reversed and stock is put back. The shift's cashier may void their own sales, others need pos.void.
+// @Tags pos
+// @Accept json
+// @Produce json
+// @Param order_id path string true "Order ID"
+// @Param request body models.BodyVoidSaleRequest true "Reason"
+// @Success 200 {object} models.OrderResponse
+// @Router /pos/sales/{order_id}/void [post]
+// @Security BearerAuth
+func VoidPOSSale(c *fiber.Ctx) error {
+	var body models.BodyVoidSaleRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	body.Reason = strings.TrimSpace(body.Reason)
+	if body.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A reason is required"})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	canVoidAny := middleware.HasPermission(c, models.PermPOSVoid)
+	var order models.Order
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", c.Params("order_id")).First(&order).Error; err != nil {
+			return err
+		}
+		if order.Channel != models.ChannelPOS || order.ShiftID == nil {
+			return errNotCounterSale
+		}
+		if order.Status == orderCancelled {
+			return errAlreadyVoided
+		}
+		shift, err := module.LockOpenShift(tx, *order.ShiftID)
+		if err != nil {
+			return err
+		}
+		if !canVoidAny && shift.CashierID.String() != actorID {
+			return errVoidNotAllowed
+		}
+
+		before := order.Status
+		order.Status = orderCancelled
+		if err := tx.Model(&order).Update("status", order.Status).Error; err != nil {
+			return err
+		}
+		if err := module.ReverseOrderPoints(tx, &order); err != nil {
+			return err
+		}
+		if err := module.RefundOrderPayments(tx, &order); err != nil {
+			return err
+		}
+		var items []models.OrderItem
+		if err := tx.Where("order_id = ?", order.ID).Find(&items).Error; err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := tx.Model(&models.Product{}).Where("id = ? AND NOT sold_by_weight", item.ProductID).
+				UpdateColumn("stock", gorm.Expr("stock + ?", item.Quantity)).Error; err != nil {
+				return err
+			}
+		}
+		if order.UserID != nil {
+			if _, err := module.RecalculateTiers(tx, order.UserID, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		entry := module.NewAuditLog(actorID, "pos.void", "order", order.ID,
+			fiber.Map{"status": before}, fiber.Map{"status": order.Status, "reason": body.Reason})
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, errNotCounterSale):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Counter sale not found"})
+	case errors.Is(err, errAlreadyVoided):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Sale is already void"})
+	case errors.Is(err, module.ErrShiftClosed):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "The sale's shift is closed"})
+	case errors.Is(err, errVoidNotAllowed):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only the shift's cashier or a manager may void this sale"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to void sale"})
+	}
+
+	if err := db.DB.Preload("Items").Preload("Payments").Where("id = ?", order.ID).First(&order).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch order"})
+	}
+	return c.Status(fiber.StatusOK).JSON(order.ToResponse())
+}
+
+// customerByPhone finds the one member with the phone number, with their tier.
+func customerByPhone(raw string) (models.User, error) {
+	phone := strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(raw))
+	if strings.HasPrefix(phone, "+66") {
+		phone = "0" + phone[3:]
+	}
+	if len(phone) != 10 || !isDigitsOnly(phone) {
+		return models.User{}, errCustomerNotFound
+	}
+	var users []models.User
+	if err := db.DB.Preload("Tier").Where("phone_number = ?", phone).Limit(2).Find(&users).Error; err != nil {
+		return models.User{}, err
+	}
+	switch len(users) {
+	case 0:
+		return models.User{}, errCustomerNotFound
+	case 1:
+		return users[0], nil
+	}
+	return models.User{}, errCustomerAmbiguous
+}
+
+func displayName(user *models.User) string {
+	if user.Name != nil && *user.Name != "" {
+		return *user.Name
+	}
+	return user.Username
+}
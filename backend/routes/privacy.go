@@ -0,0 +1,138 @@
+package routes
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"time"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/middleware"
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// ExportMyData godoc
+// @Summary Download my data
+// @Description ZIP of JSON files with the profile, addresses, orders, cart, sessions, store credit and loyalty points of the authenticated user
+// @Tags user
+// @Produce application/zip
+// @Success 200 {file} file
+// @Router /user/me/export [get]
+// @Security BearerAuth
+func ExportMyData(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	var buf bytes.Buffer
+	if err := module.WriteUserExport(db.DB, user.ID, &buf); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to export data"})
+	}
+
+	// kept as the record that the request was answered
+	now := time.Now()
+	record := module.NewDataRequest(user.ID, models.DataRequestExport, "self-service")
+	record.Status = models.DataRequestCompleted
+	record.CompletedAt = &now
+	record.HandledBy = &user.ID
+	if err := db.DB.Create(&record).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record request"})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="my-data-`+now.Format("20060102")+`.zip"`)
+	return c.Status(fiber.StatusOK).Send(buf.Bytes())
+}
+
+// DeleteMyAccount godoc
+// @Summary Delete my account
+// @Description Erases the authenticated user's personal data; orders are kept without it for the shop's accounts. With orders still open the request is queued for staff and done by due_at. Staff accounts are removed by an admin instead.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body models.BodyDeleteAccountRequest true "Current password and optional reason"
+// @Success 200 {object} models.DeleteAccountResponse
+// @Success 202 {object} models.DeleteAccountResponse
+// @Router /user/me [delete]
+// @Security BearerAuth
+func DeleteMyAccount(c *fiber.Ctx) error {
+	var body models.BodyDeleteAccountRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.Password)); err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Password is incorrect"})
+	}
+	if models.IsStaff(user.Role) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Staff accounts are removed by an administrator"})
+	}
+
+	var pending models.DataRequest
+	err = db.DB.Where("user_id = ? AND type = ? AND status = ?", user.ID, models.DataRequestDeletion, models.DataRequestPending).First(&pending).Error
+	if err == nil {
+		return c.Status(fiber.StatusAccepted).JSON(models.DeleteAccountResponse{
+			Message: "Your deletion request is already queued",
+			Queued:  true,
+			DueAt:   &pending.DueAt,
+		})
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+
+	open, err := module.HasOpenOrders(db.DB, user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+	record := module.NewDataRequest(user.ID, models.DataRequestDeletion, strings.TrimSpace(body.Reason))
+	if open {
+		if err := db.DB.Create(&record).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record request"})
+		}
+		return c.Status(fiber.StatusAccepted).JSON(models.DeleteAccountResponse{
+			Message: "You have orders in progress; your account will be deleted once they are done",
+			Queued:  true,
+			DueAt:   &record.DueAt,
+		})
+	}
+
+	var slips []string
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		record.Status = models.DataRequestCompleted
+		record.CompletedAt = &now
+		record.HandledBy = &user.ID
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		var err error
+		if slips, err = module.AnonymizeUser(tx, user.ID); err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(user.ID.String(), "user.anonymize", "user", user.ID.String(), nil, nil)
+		middleware.AuditRequest(c, &entry)
+		entry.IP = "" // the user's IPs were just scrubbed
+		return module.RecordAudit(tx, entry)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete account"})
+	}
+	module.RemoveSlips(c.UserContext(), db.Storage, slips)
+	db.Attempts.Reset(c.UserContext(), module.UserAttemptKey(user.Username))
+	clearAuthCookies(c)
+
+	return c.Status(fiber.StatusOK).JSON(models.DeleteAccountResponse{
+		Message: "Your account has been deleted",
+	})
+}
@@ -2,13 +2,53 @@ package routes
 
 import (
 	"Bakery_Pos/db"
+	"Bakery_Pos/middleware"
 	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// callerTier returns the membership tier of the logged-in caller, or nil for
+// guests, API keys and users without one.
+func callerTier(c *fiber.Ctx) *models.MembershipTier {
+	userID, _ := c.Locals("userid").(string)
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil
+	}
+	tier, err := module.UserTier(db.DB, id)
+	if err != nil {
+		return nil
+	}
+	return tier
+}
+
+// earlyAccessAllowed reports whether the caller may see products still in
+// early access: staff who edit products and members of an early access tier.
+func earlyAccessAllowed(c *fiber.Ctx, tier *models.MembershipTier) bool {
+	return middleware.HasPermission(c, models.PermProductsWrite) || (tier != nil && tier.EarlyAccess)
+}
+
+// productResponse is the product as the caller sees it, with their member
+// price when their tier has a discount. p needs its Promotions loaded.
+func productResponse(p *models.Product, tier *models.MembershipTier) models.ProductResponse {
+	resp := p.ToResponse()
+	if tier != nil && tier.DiscountPercent > 0 {
+		price := math.Round(p.MemberPrice(module.TierPricingFor(tier))*100) / 100
+		resp.MemberPrice = &price
+	}
+	return resp
+}
+
 // GetProducts godoc
 // @Summary Get all products
 // @Description Retrieve all products with optional filters
@@ -20,6 +60,8 @@ import (
 // @Param simple query bool false "Return lightweight list (id,name,tag) for selection"
 // @Param limit query int false "Number of products per page (default 20)"
 // @Param page query int false "Page number (default 1)"
+// @Param exclude_allergens query string false "Comma separated allergens the product must not contain, e.g. nuts,gluten,dairy"
+// @Param diet query string false "Comma separated dietary labels the product must have, e.g. vegan,halal,gluten_free"
 // @Success 200 {array} models.ProductResponse
 // @Success 200 {array} object "When `simple=true` returns array of {id,name,tag} objects"
 // @Router /products [get]
@@ -36,17 +78,41 @@ func GetProducts(c *fiber.Ctx) error {
 	simple := c.QueryBool("simple", false)
 	q := c.Query("q", "")
 
+	tier := callerTier(c)
 	query := db.DB.Order("updated_at DESC")
 	if !simple {
-		query = query.Preload("Images")
+		query = query.Preload("Images", models.ImagesByPosition).Preload("Promotions")
 	}
 	if lowStock {
 		query = query.Where("stock < ?", 10)
 	}
+	if !earlyAccessAllowed(c, tier) {
+		query = query.Where("early_access_until IS NULL OR early_access_until <= ?", time.Now())
+	}
 	if q != "" {
 		like := fmt.Sprintf("%%%s%%", q)
 		query = query.Where("name LIKE ? OR tag LIKE ?", like, like)
 	}
+	if v := c.Query("exclude_allergens", ""); v != "" {
+		for _, name := range strings.Split(v, ",") {
+			columns, ok := models.AllergenColumns(name)
+			if !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Unknown allergen %q", name)})
+			}
+			for _, col := range columns {
+				query = query.Where(col+" = ?", false)
+			}
+		}
+	}
+	if v := c.Query("diet", ""); v != "" {
+		for _, name := range strings.Split(v, ",") {
+			col, ok := models.DietaryColumn(name)
+			if !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Unknown dietary label %q", name)})
+			}
+			query = query.Where(col+" = ?", true)
+		}
+	}
 
 	// pagination
 	offset := (page - 1) * limit
@@ -70,7 +136,7 @@ func GetProducts(c *fiber.Ctx) error {
 
 	responses := make([]models.ProductResponse, len(products))
 	for i := range products {
-		responses[i] = products[i].ToResponse()
+		responses[i] = productResponse(&products[i], tier)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(responses)
@@ -89,17 +155,23 @@ func GetProductByID(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var product models.Product
 	// Change "images" to "Images" to match the struct field name
-	if err := db.DB.Preload("Images").First(&product, id).Error; err != nil {
+	if err := db.DB.Preload("Images", models.ImagesByPosition).Preload("Promotions").First(&product, id).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Product not found",
 		})
 	}
-	return c.Status(fiber.StatusOK).JSON(product.ToResponse())
+	tier := callerTier(c)
+	if product.EarlyAccess(time.Now()) && !earlyAccessAllowed(c, tier) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product not found",
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(productResponse(&product, tier))
 }
 
 // GetImagesProduct godoc
 // @Summary Get all images for a product
-// @Description Retrieve all images for a product, sorted by order ascending
+// @Description Retrieve all images for a product, sorted by position ascending
 // @Tags product-images
 // @Accept json
 // @Produce json
@@ -119,7 +191,7 @@ func GetImagesProduct(c *fiber.Ctx) error {
 	}
 
 	var images []models.Image
-	if err := db.DB.Where("product_id = ?", productID).Order("`order` ASC").Find(&images).Error; err != nil {
+	if err := db.DB.Scopes(models.ImagesByPosition).Where("product_id = ?", productID).Find(&images).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
@@ -128,13 +200,11 @@ func GetImagesProduct(c *fiber.Ctx) error {
 	for i := range images {
 		img := &images[i]
 		if img.PublicURL == nil || *img.PublicURL == "" {
-			SignedURL, PublicURL, _ := db.Storage.GenerateUploadURL("test", img.FilePath)
-			if SignedURL != "" {
-				img.PublicURL = &SignedURL
+			publicURL := db.Storage.GetPublicURL(module.ProductImageBucket, img.FilePath)
+			img.PublicURL = &publicURL
 
-				if err := db.DB.Model(img).Update("public_url", PublicURL).Error; err != nil {
-					fmt.Println("Failed to update public URL for image", img.ID, ":", err)
-				}
+			if err := db.DB.Model(img).Update("public_url", publicURL).Error; err != nil {
+				fmt.Println("Failed to update public URL for image", img.ID, ":", err)
 			}
 		}
 
@@ -145,3 +215,86 @@ func GetImagesProduct(c *fiber.Ctx) error {
 		Images: responses,
 	})
 }
+
+// LookupProduct godoc
+// @Summary Look up a product by scanned code
+// @Description Resolve an EAN-13/UPC-A barcode, SKU or in-store weight/price barcode (prefix 20-29) to a product. For in-store barcodes prefixes 20-24 embed the weight in grams and 25-29 the price in satang; the decoded quantity and line price are returned.
+// @Tags product
+// @Produce json
+// @Param code query string true "Scanned barcode or SKU"
+// @Success 200 {object} models.ProductLookupResponse
+// @Router /products/lookup [get]
+func LookupProduct(c *fiber.Ctx) error {
+	code := strings.TrimSpace(c.Query("code"))
+	if code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "code is required"})
+	}
+
+	resp := models.ProductLookupResponse{
+		Code:     code,
+		Quantity: 1,
+		Unit:     "piece",
+	}
+
+	query := db.DB.Preload("Images", models.ImagesByPosition).Preload("Promotions")
+	product, match, instore, err := productByCode(query, code)
+	resp.Match = match
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Product not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to look up product"})
+	}
+	tier := callerTier(c)
+	if product.EarlyAccess(time.Now()) && !earlyAccessAllowed(c, tier) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Product not found"})
+	}
+
+	// without a tier discount MemberPrice is FinalPrice
+	unitPrice := product.MemberPrice(module.TierPricingFor(tier))
+	resp.Price = unitPrice
+	if product.SoldByWeight {
+		resp.Unit = "kg"
+	}
+
+	if instore != nil {
+		switch instore.Kind {
+		case module.InStoreWeight:
+			resp.Quantity = float64(instore.Value) / 1000
+			resp.Price = math.Round(unitPrice*resp.Quantity*100) / 100
+		case module.InStorePrice:
+			resp.Price = float64(instore.Value) / 100
+			if unitPrice > 0 {
+				resp.Quantity = math.Round(resp.Price/unitPrice*1000) / 1000
+			}
+		}
+	}
+
+	resp.Product = productResponse(&product, tier)
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// productByCode finds the active product for a scanned EAN-13/UPC-A barcode,
+// in-store barcode or SKU and says which one matched. For in-store barcodes the
+// decoded weight or price is returned too. An all-digit SKU can pass the GTIN
+// check digit by chance, so a code no barcode or PLU matches is tried as a SKU.
+func productByCode(query *gorm.DB, code string) (models.Product, string, *module.InStoreBarcode, error) {
+	var product models.Product
+	gtin, gtinErr := module.NormalizeGTIN(code)
+	instore, isInStore := module.DecodeInStoreBarcode(gtin)
+
+	switch {
+	case gtinErr == nil && isInStore:
+		err := query.Session(&gorm.Session{}).Where("is_active AND plu = ?", instore.PLU).First(&product).Error
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return product, "plu", &instore, err
+		}
+	case gtinErr == nil:
+		err := query.Session(&gorm.Session{}).Where("is_active AND barcode = ?", gtin).First(&product).Error
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return product, "barcode", nil, err
+		}
+	}
+	err := query.Session(&gorm.Session{}).Where("is_active AND sku = ?", code).First(&product).Error
+	return product, "sku", nil, err
+}
@@ -0,0 +1,238 @@
+package routes
+
+import (
+	"errors"
+	"time"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// refreshCookie holds the refresh token. It is only sent to /api/user, where
+// the refresh and logout endpoints live.
+const (
+	refreshCookie     = "Refresh"
+	refreshCookiePath = "/api/user"
+)
+
+// Clients that cannot keep cookies, such as the in-store tablet app, send
+// X-Auth-Transport: header. They get both tokens in the response body, send
+// the access token as Authorization: Bearer and the refresh token in the
+// refresh and logout request bodies.
+const (
+	authTransportHeader = "X-Auth-Transport"
+	headerTransport     = "header"
+)
+
+// startSession creates a session for user, sets the access and refresh cookies
+// and returns the user response with the access token expiry.
+func startSession(c *fiber.Ctx, user *models.User) (models.UserResponse, error) {
+	session, refresh, err := module.NewSession(db.DB, user, c.IP(), c.Get(fiber.HeaderUserAgent))
+	if err != nil {
+		return models.UserResponse{}, err
+	}
+	return issueTokens(c, user, session, refresh)
+}
+
+func issueTokens(c *fiber.Ctx, user *models.User, session models.Session, refresh string) (models.UserResponse, error) {
+	EXP := time.Now().Add(module.AccessTokenTTL)
+	tokenString, err := module.GenerateJWT(user, session.ID, EXP)
+	if err != nil {
+		return models.UserResponse{}, err
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     "Authorization",
+		Value:    "Bearer " + tokenString,
+		Expires:  EXP,
+		HTTPOnly: true,
+		Secure:   false,
+		SameSite: "None",
+		Path:     "/",
+	})
+	c.Cookie(&fiber.Cookie{
+		Name:     refreshCookie,
+		Value:    refresh,
+		Expires:  session.ExpiresAt,
+		HTTPOnly: true,
+		Secure:   false,
+		SameSite: "None",
+		Path:     refreshCookiePath,
+	})
+
+	resp := user.ToResponse()
+	resp.Exp = &[]int64{EXP.Unix()}[0]
+	if c.Get(authTransportHeader) == headerTransport {
+		resp.AccessToken = &tokenString
+		resp.RefreshToken = &refresh
+	}
+	return resp, nil
+}
+
+func clearAuthCookies(c *fiber.Ctx) {
+	for name, path := range map[string]string{"Authorization": "/", refreshCookie: refreshCookiePath} {
+		c.Cookie(&fiber.Cookie{
+			Name:     name,
+			Value:    "",
+			Expires:  time.Now().Add(-time.Hour),
+			HTTPOnly: true,
+			Secure:   false,
+			SameSite: "None",
+			Path:     path,
+		})
+	}
+}
+
+// refreshToken returns the refresh token from the request body, falling back
+// to the refresh cookie.
+func refreshToken(c *fiber.Ctx) string {
+	var body models.BodyRefreshRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err == nil && body.RefreshToken != "" {
+			return body.RefreshToken
+		}
+	}
+	return c.Cookies(refreshCookie)
+}
+
+// RefreshHandler godoc
+// @Summary Refresh the access token
+// @Description Exchanges the refresh token (cookie, or refresh_token in the body) for a new access token and a rotated refresh token. Reusing an old refresh token revokes the session.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body models.BodyRefreshRequest false "Refresh token for clients without cookies"
+// @Success 200 {object} models.UserResponse
+// @Router /user/refresh [post]
+func RefreshHandler(c *fiber.Ctx) error {
+	token := refreshToken(c)
+	if token == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Missing refresh token",
+		})
+	}
+
+	// no transaction: a detected reuse must stay revoked
+	session, nextToken, err := module.RotateSession(db.DB, token, c.IP(), c.Get(fiber.HeaderUserAgent))
+	var user models.User
+	if err == nil {
+		err = db.DB.Where("id = ?", session.UserID).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && user.DisabledAt != nil) {
+			err = module.ErrAccountBlocked
+		}
+	}
+	if err != nil {
+		clearAuthCookies(c)
+		switch {
+		case errors.Is(err, module.ErrAccountBlocked):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Account is disabled"})
+		case errors.Is(err, module.ErrSessionInvalid), errors.Is(err, module.ErrSessionReused):
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Session expired, please log in again"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to refresh session"})
+	}
+
+	resp, err := issueTokens(c, &user, session, nextToken)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate token",
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetSessions godoc
+// @Summary List active sessions
+// @Description Devices the user is logged in on, most recently used first
+// @Tags user
+// @Produce json
+// @Success 200 {array} models.SessionResponse
+// @Router /user/sessions [get]
+// @Security BearerAuth
+func GetSessions(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userid").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	current, _ := c.Locals("sessionid").(string)
+
+	var sessions []models.Session
+	if err := db.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch sessions"})
+	}
+
+	resp := make([]models.SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		item := s.ToResponse()
+		item.Current = s.ID.String() == current
+		resp = append(resp, item)
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// RevokeSession godoc
+// @Summary Log out one session
+// @Tags user
+// @Produce json
+// @Param session_id path string true "Session ID"
+// @Success 200 {object} models.MessageResponse
+// @Router /user/sessions/{session_id} [delete]
+// @Security BearerAuth
+func RevokeSession(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userid").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	sessionID, err := uuid.Parse(c.Params("session_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid session ID"})
+	}
+
+	if err := module.RevokeSession(db.DB, userID, sessionID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Session not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke session"})
+	}
+	if sessionID.String() == c.Locals("sessionid") {
+		clearAuthCookies(c)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
+		Message: "Session revoked",
+	})
+}
+
+// RevokeAllSessions godoc
+// @Summary Log out everywhere
+// @Description Revokes every session of the user, including the current one, and invalidates issued access tokens
+// @Tags user
+// @Produce json
+// @Success 200 {object} models.MessageResponse
+// @Router /user/sessions/revoke-all [post]
+// @Security BearerAuth
+func RevokeAllSessions(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userid").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		return module.RevokeAllSessions(tx, userID)
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke sessions"})
+	}
+	clearAuthCookies(c)
+
+	return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
+		Message: "Logged out on all devices",
+	})
+}
@@ -0,0 +1,189 @@
+package routes
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/middleware"
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OpenShift godoc
+// @Summary Open a shift
+// @Description Starts the cashier's shift with the starting float in the drawer. A cashier has one open shift at a time.
+// @Tags pos
+// @Accept json
+// @Produce json
+// @Param request body models.BodyOpenShiftRequest true "Starting float"
+// @Success 201 {object} models.Shift
+// @Router /pos/shifts [post]
+// @Security BearerAuth
+func OpenShift(c *fiber.Ctx) error {
+	cashierID, err := uuid.Parse(c.Locals("userid").(string))
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Shifts need a cashier account"})
+	}
+	var body models.BodyOpenShiftRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	if body.OpeningFloat < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "opening_float cannot be negative"})
+	}
+
+	var shift models.Shift
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if shift, err = module.OpenShift(tx, cashierID, body.OpeningFloat, strings.TrimSpace(body.Register)); err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(cashierID.String(), "shift.open", "shift", strconv.Itoa(int(shift.ID)), nil, shift)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if errors.Is(err, module.ErrShiftAlreadyOpen) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "You already have an open shift"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to open shift"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(shift)
+}
+
+// GetCurrentShift godoc
+// @Summary My open shift so far
+// @Description The shift report of the cashier's open shift, with the cash the drawer should hold now
+// @Tags pos
+// @Produce json
+// @Success 200 {object} models.ShiftReport
+// @Router /pos/shifts/current [get]
+// @Security BearerAuth
+func GetCurrentShift(c *fiber.Ctx) error {
+	shift, ok, err := currentShift(c)
+	if !ok {
+		return err
+	}
+	report, err := module.BuildShiftReport(db.DB, shift)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to build shift report"})
+	}
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// AddCashMovement godoc
+// @Summary Record cash in or out
+// @Description Cash put into or taken out of the drawer outside a sale, e.g. a bank drop
+// @Tags pos
+// @Accept json
+// @Produce json
+// @Param request body models.BodyCashMovementRequest true "Movement"
+// @Success 201 {object} models.CashMovement
+// @Router /pos/shifts/current/cash [post]
+// @Security BearerAuth
+func AddCashMovement(c *fiber.Ctx) error {
+	var body models.BodyCashMovementRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	body.Reason = strings.TrimSpace(body.Reason)
+	if body.Kind != models.CashIn && body.Kind != models.CashOut {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "kind must be in or out"})
+	}
+	if body.Amount <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "amount must be above 0"})
+	}
+	if body.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A reason is required"})
+	}
+
+	shift, ok, err := currentShift(c)
+	if !ok {
+		return err
+	}
+	var movement models.CashMovement
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if movement, err = module.AddCashMovement(tx, shift.ID, body.Kind, body.Amount, body.Reason, &shift.CashierID); err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(shift.CashierID.String(), "shift.cash_"+movement.Kind, "shift", strconv.Itoa(int(shift.ID)), nil, movement)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if errors.Is(err, module.ErrShiftClosed) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Shift is closed"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record cash movement"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(movement)
+}
+
+// CloseShift godoc
+// @Summary Close my shift
+// @Description Records the counted cash and returns the final shift report with the over/short against the expected cash
+// @Tags pos
+// @Accept json
+// @Produce json
+// @Param request body models.BodyCloseShiftRequest true "Counted cash"
+// @Success 200 {object} models.ShiftReport
+// @Router /pos/shifts/current/close [post]
+// @Security BearerAuth
+func CloseShift(c *fiber.Ctx) error {
+	var body models.BodyCloseShiftRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	if body.CountedCash < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "counted_cash cannot be negative"})
+	}
+
+	shift, ok, err := currentShift(c)
+	if !ok {
+		return err
+	}
+	var report models.ShiftReport
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		closed, err := module.CloseShift(tx, shift.ID, body.CountedCash, strings.TrimSpace(body.Note), &shift.CashierID)
+		if err != nil {
+			return err
+		}
+		if report, err = module.BuildShiftReport(tx, closed); err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(shift.CashierID.String(), "shift.close", "shift", strconv.Itoa(int(closed.ID)), shift, closed)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if errors.Is(err, module.ErrShiftClosed) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Shift is already closed"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to close shift"})
+	}
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// currentShift loads the caller's open shift. When ok is false the error
+// response has been written and err is what the handler returns.
+func currentShift(c *fiber.Ctx) (models.Shift, bool, error) {
+	cashierID, err := uuid.Parse(c.Locals("userid").(string))
+	if err != nil {
+		return models.Shift{}, false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Shifts need a cashier account"})
+	}
+	shift, err := module.CurrentShift(db.DB, cashierID)
+	if errors.Is(err, module.ErrNoOpenShift) {
+		return shift, false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "You have no open shift"})
+	}
+	if err != nil {
+		return shift, false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load shift"})
+	}
+	return shift, true, nil
+}
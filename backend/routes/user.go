@@ -6,7 +6,6 @@ import (
 	"Bakery_Pos/module"
 	"errors"
 	"strings"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/crypto/bcrypt"
@@ -38,8 +37,14 @@ func RegisterHandler(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := module.ValidatePassword(req.Password, req.Username); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := module.HashPassword(req.Password)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to hash password",
@@ -49,7 +54,7 @@ func RegisterHandler(c *fiber.Ctx) error {
 	// Create user struct
 	user := &models.User{
 		Username: req.Username,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
 	}
 
 	// Save user to DB
@@ -64,40 +69,25 @@ func RegisterHandler(c *fiber.Ctx) error {
 		})
 	}
 
-	// Generate token
-	EXP := time.Now().Add(24 * time.Hour)
-	tokenString, err := module.GenerateJWT(user, EXP)
+	resp, err := startSession(c, user)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate token",
 		})
 	}
 
-	// Set cookie
-	c.Cookie(&fiber.Cookie{
-		Name:     "Authorization",
-		Value:    "Bearer " + tokenString,
-		Expires:  EXP,
-		HTTPOnly: true,
-		Secure:   false,
-		SameSite: "None",
-		Path:     "/",
-	})
-
-	resp := user.ToResponse()
-	resp.Exp = &[]int64{EXP.Unix()}[0]
-
 	return c.Status(fiber.StatusCreated).JSON(resp)
 }
 
 // LoginHandler godoc
 // @Summary Login user
-// @Description Authenticate user and return JWT token
+// @Description Authenticate user and return JWT token. Repeated failures per username or IP are slowed down and then locked (429 with Retry-After). Users with 2FA get an mfa_token to complete at /user/login/mfa instead.
 // @Tags user
 // @Accept json
 // @Produce json
 // @Param request body models.FormRequest true "User login data"
 // @Success 200 {object} models.UserResponse
+// @Success 202 {object} models.LoginMFAResponse
 // @Router /user/login [post]
 func LoginHandler(c *fiber.Ctx) error {
 	var req models.FormRequest
@@ -107,9 +97,14 @@ func LoginHandler(c *fiber.Ctx) error {
 		})
 	}
 
+	if blocked, err := throttleLogin(c, req.Username); blocked {
+		return err
+	}
+
 	var user models.User
 	if err := db.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			recordLoginFailure(c, req.Username, "")
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Invalid username or password",
 			})
@@ -120,53 +115,48 @@ func LoginHandler(c *fiber.Ctx) error {
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		recordLoginFailure(c, req.Username, user.ID.String())
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid username or password",
 		})
 	}
+	resetLoginFailures(c, req.Username)
+
+	if user.DisabledAt != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Account is disabled",
+		})
+	}
+
+	if user.MFAEnabledAt != nil {
+		return mfaChallenge(c, &user)
+	}
 
-	EXP := time.Now().Add(24 * time.Hour)
-	tokenString, err := module.GenerateJWT(&user, EXP)
+	resp, err := startSession(c, &user)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate token",
 		})
 	}
 
-	c.Cookie(&fiber.Cookie{
-		Name:     "Authorization",
-		Value:    "Bearer " + tokenString,
-		Expires:  EXP,
-		HTTPOnly: true,
-		Secure:   false,
-		SameSite: "None",
-		Path:     "/",
-	})
-
-	resp := user.ToResponse()
-	resp.Exp = &[]int64{EXP.Unix()}[0]
-
 	return c.Status(fiber.StatusOK).JSON(resp)
 }
 
 // LogoutHandler godoc
 // @Summary Logout user
-// @Description Remove JWT token from cookies (logout)
+// @Description Revoke the current session (refresh cookie, or refresh_token in the body) and remove the token cookies
 // @Tags user
 // @Produce json
 // @Success 200 {object} models.MessageResponse
 // @Router /user/logout [post]
 // @Security BearerAuth
 func LogoutHandler(c *fiber.Ctx) error {
-	c.Cookie(&fiber.Cookie{
-		Name:     "Authorization",
-		Value:    "",
-		Expires:  time.Now().Add(-time.Hour),
-		HTTPOnly: true,
-		Secure:   false,
-		SameSite: "None",
-		Path:     "/",
-	})
+	// best effort, the cookies are cleared either way; a token that does not
+	// match its session is ignored
+	if token := refreshToken(c); token != "" {
+		module.EndSession(db.DB, token)
+	}
+	clearAuthCookies(c)
 
 	res := models.MessageResponse{
 		Message: "Logged out successfully",
@@ -175,14 +165,40 @@ func LogoutHandler(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(res)
 }
 
+// GetProfile godoc
+// @Summary Get the current user's profile
+// @Description Profile of the authenticated user with saved delivery addresses
+// @Tags user
+// @Produce json
+// @Success 200 {object} models.ProfileResponse
+// @Router /user/me [get]
+// @Security BearerAuth
+func GetProfile(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	resp, err := profileResponse(&user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch addresses",
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
 // UpdateSetting godoc
-// @Summary Update user settings
-// @Description Update phone number or place for authenticated user
+// @Summary Update the current user's profile
+// @Description Update name, phone number or place for authenticated user. Omitted fields are left unchanged.
 // @Tags user
 // @Accept json
 // @Produce json
 // @Param request body models.FormSetting true "Update settings"
-// @Success 200 {object} models.MessageResponse
+// @Success 200 {object} models.ProfileResponse
+// @Router /user/me [put]
 // @Router /user/settings [put]
 // @Security BearerAuth
 func UpdateSetting(c *fiber.Ctx) error {
@@ -214,6 +230,17 @@ func UpdateSetting(c *fiber.Ctx) error {
 	}
 
 	updated := false
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" || len(name) > 100 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Name must be 1 to 100 characters",
+			})
+		}
+		user.Name = &name
+		updated = true
+	}
+
 	if input.PhoneNumber != nil {
 		phone := strings.TrimSpace(*input.PhoneNumber)
 		if len(phone) != 10 || !isDigitsOnly(phone) {
@@ -249,10 +276,31 @@ func UpdateSetting(c *fiber.Ctx) error {
 		})
 	}
 
-	res := models.MessageResponse{
-		Message: "User updated successfully",
+	resp, err := profileResponse(&user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch addresses",
+		})
 	}
-	return c.Status(fiber.StatusOK).JSON(res)
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+func profileResponse(user *models.User) (models.ProfileResponse, error) {
+	addresses, err := loadAddresses(user.ID)
+	if err != nil {
+		return models.ProfileResponse{}, err
+	}
+	tier, err := module.UserTier(db.DB, user.ID)
+	if err != nil {
+		return models.ProfileResponse{}, err
+	}
+	return models.ProfileResponse{
+		UserResponse: user.ToResponse(),
+		CreatedAt:    user.CreatedAt,
+		Addresses:    addresses,
+		Tier:         tier,
+		TierSpend:    user.TierSpend,
+	}, nil
 }
 
 func isDigitsOnly(s string) bool {
@@ -0,0 +1,156 @@
+package routes_admin
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/middleware"
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetAPIKeys godoc
+// @Summary List API keys
+// @Description Includes revoked and expired keys. Only the prefix of each key is shown.
+// @Tags api-key
+// @Produce json
+// @Success 200 {array} models.APIKeyResponse
+// @Router /admin/api-keys [get]
+func GetAPIKeys(c *fiber.Ctx) error {
+	var keys []models.APIKey
+	if err := db.DB.Order("created_at DESC").Find(&keys).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch API keys"})
+	}
+
+	resp := make([]models.APIKeyResponse, len(keys))
+	for i := range keys {
+		resp[i] = keys[i].ToResponse()
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Scopes are permissions from /admin/roles, except users.manage, and must be held by the caller. The full key is returned once; send it in the X-API-Key header.
+// @Tags api-key
+// @Accept json
+// @Produce json
+// @Param request body models.BodyAPIKeyRequest true "Key name, scopes, rate limit and expiry"
+// @Success 201 {object} models.APIKeyCreatedResponse
+// @Router /admin/api-keys [post]
+func CreateAPIKey(c *fiber.Ctx) error {
+	var body models.BodyAPIKeyRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" || len(body.Name) > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Name is required and at most 100 characters"})
+	}
+	if err := module.ValidateScopes(body.Scopes); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	role, _ := c.Locals("role").(string)
+	for _, scope := range body.Scopes {
+		if !models.HasPermission(role, scope) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Cannot grant a permission you do not hold: " + scope})
+		}
+	}
+	if body.RateLimit < 0 || body.RateLimit > 10000 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Rate limit must be between 0 (default) and 10000 requests per minute"})
+	}
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Expiry must be in the future"})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	creator, err := uuid.Parse(actorID)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "API keys can only be created by a user"})
+	}
+
+	key := models.APIKey{
+		Name:      body.Name,
+		Scopes:    strings.Join(dedupe(body.Scopes), ","),
+		RateLimit: body.RateLimit,
+		CreatedBy: creator,
+		ExpiresAt: body.ExpiresAt,
+	}
+	var raw string
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if raw, err = module.CreateAPIKey(tx, &key); err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(actorID, "apikey.create", "api_key", key.ID.String(), nil, key.ToResponse())
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create API key"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.APIKeyCreatedResponse{
+		APIKeyResponse: key.ToResponse(),
+		Key:            raw,
+	})
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Tags api-key
+// @Produce json
+// @Param key_id path string true "API key ID"
+// @Success 200 {object} models.MessageResponse
+// @Router /admin/api-keys/{key_id} [delete]
+func RevokeAPIKey(c *fiber.Ctx) error {
+	keyID, err := uuid.Parse(c.Params("key_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid API key ID"})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.APIKey{}).
+			Where("id = ? AND revoked_at IS NULL", keyID).
+			Update("revoked_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		entry := module.NewAuditLog(actorID, "apikey.revoke", "api_key", keyID.String(), nil, nil)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "API key not found or already revoked"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke API key"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
+		Message: "API key revoked",
+	})
+}
+
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := values[:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package routes_admin
+
+import (
+	"Bakery_Pos/db"
+	"Bakery_Pos/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetAuditLogs godoc
+// @Summary List audit log entries
+// @Description Newest first. Filter by entity_type, entity_id, action, actor_id or api_key_id.
+// @Tags audit
+// @Produce json
+// @Param entity_type query string false "Entity type, e.g. product"
+// @Param entity_id query string false "Entity ID"
+// @Param action query string false "Action, e.g. product.bulk_update"
+// @Param actor_id query string false "User ID of the actor"
+// @Param api_key_id query string false "ID of the API key used"
+// @Param limit query int false "Page size (default 50)"
+// @Param page query int false "Page number (default 1)"
+// @Success 200 {object} models.AuditLogListResponse
+// @Router /admin/audit-logs [get]
+func GetAuditLogs(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	page := c.QueryInt("page", 1)
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	query := db.DB.Model(&models.AuditLog{})
+	if v := c.Query("entity_type"); v != "" {
+		query = query.Where("entity_type = ?", v)
+	}
+	if v := c.Query("entity_id"); v != "" {
+		query = query.Where("entity_id = ?", v)
+	}
+	if v := c.Query("action"); v != "" {
+		query = query.Where("action = ?", v)
+	}
+	if v := c.Query("actor_id"); v != "" {
+		query = query.Where("actor_id = ?", v)
+	}
+	if v := c.Query("api_key_id"); v != "" {
+		query = query.Where("api_key_id = ?", v)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to count audit logs"})
+	}
+
+	logs := []models.AuditLog{}
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset((page - 1) * limit).Find(&logs).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch audit logs"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.AuditLogListResponse{
+		Data:  logs,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	})
+}
@@ -0,0 +1,293 @@
+package routes_admin
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/middleware"
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	errRequestClosed = errors.New("data request is not pending")
+	errOpenOrders    = errors.New("user has open orders")
+)
+
+// GetDataRequests godoc
+// @Summary List PDPA data requests
+// @Description Export and deletion requests, pending ones by due date first. overdue=true keeps pending requests past their due date.
+// @Tags data-request
+// @Produce json
+// @Param status query string false "pending, completed or rejected"
+// @Param type query string false "export or deletion"
+// @Param overdue query bool false "Only overdue pending requests"
+// @Param limit query int false "Page size (default 50)"
+// @Param page query int false "Page number (default 1)"
+// @Success 200 {object} models.DataRequestListResponse
+// @Router /admin/data-requests [get]
+func GetDataRequests(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	page := c.QueryInt("page", 1)
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	query := db.DB.Model(&models.DataRequest{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("data_requests.status = ?", status)
+	}
+	if kind := c.Query("type"); kind != "" {
+		query = query.Where("data_requests.type = ?", kind)
+	}
+	if c.QueryBool("overdue") {
+		query = query.Where("data_requests.status = ? AND data_requests.due_at < ?", models.DataRequestPending, time.Now())
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to count data requests"})
+	}
+
+	var rows []struct {
+		models.DataRequest
+		Username string
+	}
+	if err := query.
+		Select("data_requests.*, users.username").
+		Joins("LEFT JOIN users ON users.id = data_requests.user_id").
+		Order("data_requests.status = 'pending' DESC, data_requests.due_at ASC").
+		Limit(limit).Offset((page - 1) * limit).
+		Scan(&rows).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch data requests"})
+	}
+
+	now := time.Now()
+	resp := models.DataRequestListResponse{
+		Data:  make([]models.DataRequestResponse, 0, len(rows)),
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	}
+	for _, row := range rows {
+		resp.Data = append(resp.Data, models.DataRequestResponse{
+			DataRequest: row.DataRequest,
+			Username:    row.Username,
+			Overdue:     row.Status == models.DataRequestPending && row.DueAt.Before(now),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// CreateDataRequest godoc
+// @Summary Log a data request received outside the app
+// @Description E.g. by email or at the counter. It is due in 30 days.
+// @Tags data-request
+// @Accept json
+// @Produce json
+// @Param request body models.BodyDataRequestRequest true "User, type and note"
+// @Success 201 {object} models.DataRequest
+// @Router /admin/data-requests [post]
+func CreateDataRequest(c *fiber.Ctx) error {
+	var body models.BodyDataRequestRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	if body.Type != models.DataRequestExport && body.Type != models.DataRequestDeletion {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "type must be export or deletion"})
+	}
+
+	var user models.User
+	if err := db.DB.Where("id = ?", body.UserID).First(&user).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	request := module.NewDataRequest(user.ID, body.Type, strings.TrimSpace(body.Note))
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&request).Error; err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(actorID, "data_request.create", "data_request", strconv.Itoa(int(request.ID)), nil, request)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create data request"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(request)
+}
+
+// ExportDataRequest godoc
+// @Summary Download the data of a request's user
+// @Description Same ZIP as /user/me/export, to send to the customer
+// @Tags data-request
+// @Produce application/zip
+// @Param request_id path int true "Data request ID"
+// @Success 200 {file} file
+// @Router /admin/data-requests/{request_id}/export [get]
+func ExportDataRequest(c *fiber.Ctx) error {
+	var request models.DataRequest
+	if ferr := findDataRequest(c, &request); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	var buf bytes.Buffer
+	if err := module.WriteUserExport(db.DB, request.UserID, &buf); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to export data"})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	entry := module.NewAuditLog(actorID, "data_request.export", "data_request", strconv.Itoa(int(request.ID)), nil, nil)
+	middleware.AuditRequest(c, &entry)
+	if err := module.RecordAudit(db.DB, entry); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record audit log"})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="data-request-`+strconv.Itoa(int(request.ID))+`.zip"`)
+	return c.Status(fiber.StatusOK).Send(buf.Bytes())
+}
+
+// CompleteDataRequest godoc
+// @Summary Complete a data request
+// @Description A deletion request anonymizes the user, which needs their orders to be delivered first. An export request is only marked as sent.
+// @Tags data-request
+// @Accept json
+// @Produce json
+// @Param request_id path int true "Data request ID"
+// @Param request body models.BodyDataRequestResolve false "Note"
+// @Success 200 {object} models.DataRequest
+// @Router /admin/data-requests/{request_id}/complete [post]
+func CompleteDataRequest(c *fiber.Ctx) error {
+	return resolveDataRequest(c, models.DataRequestCompleted)
+}
+
+// RejectDataRequest godoc
+// @Summary Reject a data request
+// @Description The note should say why, e.g. the identity could not be verified
+// @Tags data-request
+// @Accept json
+// @Produce json
+// @Param request_id path int true "Data request ID"
+// @Param request body models.BodyDataRequestResolve true "Reason"
+// @Success 200 {object} models.DataRequest
+// @Router /admin/data-requests/{request_id}/reject [post]
+func RejectDataRequest(c *fiber.Ctx) error {
+	return resolveDataRequest(c, models.DataRequestRejected)
+}
+
+func resolveDataRequest(c *fiber.Ctx, status string) error {
+	var body models.BodyDataRequestResolve
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+		}
+	}
+	body.Note = strings.TrimSpace(body.Note)
+	if status == models.DataRequestRejected && body.Note == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A reason is required"})
+	}
+
+	var request models.DataRequest
+	if ferr := findDataRequest(c, &request); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	actor, _ := uuid.Parse(actorID)
+	var username string
+	var slips []string
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		before := request
+		now := time.Now()
+		res := tx.Model(&request).Where("status = ?", models.DataRequestPending).Updates(map[string]any{
+			"status":       status,
+			"completed_at": now,
+			"handled_by":   actor,
+			"note":         strings.TrimSpace(request.Note + "\n" + body.Note),
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errRequestClosed
+		}
+
+		if status == models.DataRequestCompleted && request.Type == models.DataRequestDeletion {
+			var user models.User
+			if err := tx.Unscoped().Where("id = ?", request.UserID).First(&user).Error; err != nil {
+				return err
+			}
+			if err := ensureNotLastAdmin(tx, &user); err != nil {
+				return err
+			}
+			open, err := module.HasOpenOrders(tx, user.ID)
+			if err != nil {
+				return err
+			}
+			if open {
+				return errOpenOrders
+			}
+			if slips, err = module.AnonymizeUser(tx, user.ID); err != nil {
+				return err
+			}
+			username = user.Username
+			entry := module.NewAuditLog(actorID, "user.anonymize", "user", user.ID.String(), nil, nil)
+			middleware.AuditRequest(c, &entry)
+			if err := module.RecordAudit(tx, entry); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.First(&request, request.ID).Error; err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(actorID, "data_request."+status, "data_request", strconv.Itoa(int(request.ID)), before, request)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	switch {
+	case errors.Is(err, errRequestClosed):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Request is already " + request.Status})
+	case errors.Is(err, errOpenOrders):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "User still has orders in progress"})
+	case errors.Is(err, errLastAdmin):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Cannot delete the last Admin"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update data request"})
+	}
+
+	if username != "" {
+		module.RemoveSlips(c.UserContext(), db.Storage, slips)
+		db.Attempts.Reset(c.UserContext(), module.UserAttemptKey(username))
+	}
+	return c.Status(fiber.StatusOK).JSON(request)
+}
+
+// findDataRequest loads the request in the path.
+func findDataRequest(c *fiber.Ctx, request *models.DataRequest) *fiber.Error {
+	id, err := c.ParamsInt("request_id")
+	if err != nil || id <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid data request ID")
+	}
+	if err := db.DB.First(request, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Data request not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	return nil
+}
@@ -0,0 +1,369 @@
+package routes_admin
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/middleware"
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetGiftCards godoc
+// @Summary List gift cards
+// @Description Newest first. code finds one card by its full code.
+// @Tags gift-card
+// @Produce json
+// @Param status query string false "inactive, active or void"
+// @Param code query string false "Full gift card code"
+// @Param limit query int false "Page size (default 50)"
+// @Param page query int false "Page number (default 1)"
+// @Success 200 {object} models.GiftCardListResponse
+// @Router /admin/gift-cards [get]
+func GetGiftCards(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	page := c.QueryInt("page", 1)
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	query := db.DB.Model(&models.GiftCard{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if raw := c.Query("code"); raw != "" {
+		code, err := module.NormalizeGiftCardCode(raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid gift card code"})
+		}
+		query = query.Where("code = ?", code)
+	}
+
+	resp := models.GiftCardListResponse{
+		Data:  []models.GiftCard{},
+		Page:  page,
+		Limit: limit,
+	}
+	if err := query.Count(&resp.Total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to count gift cards"})
+	}
+	if err := query.Order("id DESC").Limit(limit).Offset((page - 1) * limit).Find(&resp.Data).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch gift cards"})
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetGiftCard godoc
+// @Summary A gift card with its ledger
+// @Tags gift-card
+// @Produce json
+// @Param card_id path int true "Gift card ID"
+// @Success 200 {object} models.GiftCardResponse
+// @Router /admin/gift-cards/{card_id} [get]
+func GetGiftCard(c *fiber.Ctx) error {
+	cardID, err := c.ParamsInt("card_id")
+	if err != nil || cardID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid gift card ID"})
+	}
+
+	resp := models.GiftCardResponse{Transactions: []models.GiftCardTransaction{}}
+	if err := db.DB.First(&resp.GiftCard, cardID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Gift card not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch gift card"})
+	}
+	if err := db.DB.Where("gift_card_id = ?", cardID).Order("id DESC").Find(&resp.Transactions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch gift card transactions"})
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// IssueGiftCard godoc
+// @Summary Issue a gift card
+// @Description Creates a card with a new code. Cards sold over the counter stay inactive until activated, unless activate is set.
+// @Tags gift-card
+// @Accept json
+// @Produce json
+// @Param request body models.BodyGiftCardRequest true "Value and expiry"
+// @Success 201 {object} models.GiftCard
+// @Router /admin/gift-cards [post]
+func IssueGiftCard(c *fiber.Ctx) error {
+	var body models.BodyGiftCardRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	if body.Value <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "value must be above 0"})
+	}
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "expires_at must be in the future"})
+	}
+
+	card := models.GiftCard{
+		InitialValue: body.Value,
+		ExpiresAt:    body.ExpiresAt,
+		Note:         strings.TrimSpace(body.Note),
+	}
+	if body.Activate {
+		card.Status = models.GiftCardActive
+	}
+	actorID, _ := c.Locals("userid").(string)
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := module.IssueGiftCard(tx, &card, parseActor(actorID)); err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(actorID, "giftcard.issue", "gift_card", strconv.Itoa(int(card.ID)), nil, card)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to issue gift card"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(card)
+}
+
+// ActivateGiftCard godoc
+// @Summary Activate a gift card
+// @Description Makes an inactive card usable, once it has been paid for
+// @Tags gift-card
+// @Produce json
+// @Param card_id path int true "Gift card ID"
+// @Success 200 {object} models.GiftCard
+// @Router /admin/gift-cards/{card_id}/activate [post]
+func ActivateGiftCard(c *fiber.Ctx) error {
+	cardID, err := c.ParamsInt("card_id")
+	if err != nil || cardID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid gift card ID"})
+	}
+
+	var card models.GiftCard
+	actorID, _ := c.Locals("userid").(string)
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if card, err = module.ActivateGiftCard(tx, uint(cardID)); err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(actorID, "giftcard.activate", "gift_card", strconv.Itoa(cardID), nil, nil)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	switch {
+	case errors.Is(err, module.ErrGiftCardNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Gift card not found"})
+	case errors.Is(err, module.ErrGiftCardState):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Only inactive gift cards can be activated"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to activate gift card"})
+	}
+	return c.Status(fiber.StatusOK).JSON(card)
+}
+
+// VoidGiftCard godoc
+// @Summary Void a gift card
+// @Description Writes off the remaining balance and blocks the card for good
+// @Tags gift-card
+// @Accept json
+// @Produce json
+// @Param card_id path int true "Gift card ID"
+// @Param request body models.BodyStoredValueVoidRequest true "Reason"
+// @Success 200 {object} models.GiftCard
+// @Router /admin/gift-cards/{card_id}/void [post]
+func VoidGiftCard(c *fiber.Ctx) error {
+	cardID, err := c.ParamsInt("card_id")
+	if err != nil || cardID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid gift card ID"})
+	}
+	var body models.BodyStoredValueVoidRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	body.Reason = strings.TrimSpace(body.Reason)
+	if body.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A reason is required"})
+	}
+
+	var card models.GiftCard
+	actorID, _ := c.Locals("userid").(string)
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if card, err = module.VoidGiftCard(tx, uint(cardID), body.Reason, parseActor(actorID)); err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(actorID, "giftcard.void", "gift_card", strconv.Itoa(cardID), nil, body)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	switch {
+	case errors.Is(err, module.ErrGiftCardNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Gift card not found"})
+	case errors.Is(err, module.ErrGiftCardState):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Gift card is already void"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to void gift card"})
+	}
+	return c.Status(fiber.StatusOK).JSON(card)
+}
+
+// GetUserStoreCredit godoc
+// @Summary A user's store credit
+// @Tags gift-card
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param limit query int false "Page size (default 50)"
+// @Param page query int false "Page number (default 1)"
+// @Success 200 {object} models.StoreCreditResponse
+// @Router /admin/users/{user_id}/store-credit [get]
+func GetUserStoreCredit(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	limit := c.QueryInt("limit", 50)
+	page := c.QueryInt("page", 1)
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	resp, err := module.StoreCreditSummary(db.DB, userID, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch store credit"})
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// IssueStoreCredit godoc
+// @Summary Give a user store credit
+// @Description E.g. to refund an order. The reason is shown in the user's history.
+// @Tags gift-card
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param request body models.BodyStoreCreditRequest true "Amount and reason"
+// @Success 200 {object} models.StoreCreditEntry
+// @Router /admin/users/{user_id}/store-credit [post]
+func IssueStoreCredit(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	var body models.BodyStoreCreditRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	body.Reason = strings.TrimSpace(body.Reason)
+	if body.Amount <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "amount must be above 0"})
+	}
+	if body.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A reason is required"})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	var entry models.StoreCreditEntry
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+		if body.OrderID != nil {
+			var order models.Order
+			if err := tx.Select("id").Where("id = ? AND user_id = ?", *body.OrderID, user.ID).First(&order).Error; err != nil {
+				return errOrderNotFound
+			}
+		}
+		var err error
+		if entry, err = module.IssueStoreCredit(tx, user.ID, body.Amount, body.Reason, body.OrderID, parseActor(actorID)); err != nil {
+			return err
+		}
+		audit := module.NewAuditLog(actorID, "storecredit.issue", "user", user.ID.String(), nil, body)
+		middleware.AuditRequest(c, &audit)
+		return module.RecordAudit(tx, audit)
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	case errors.Is(err, errOrderNotFound):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Order not found for this user"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to issue store credit"})
+	}
+	return c.Status(fiber.StatusOK).JSON(entry)
+}
+
+// VoidStoreCredit godoc
+// @Summary Remove a user's store credit
+// @Description Removes amount, or the whole balance when amount is 0. The balance cannot go below zero.
+// @Tags gift-card
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param request body models.BodyStoredValueVoidRequest true "Amount and reason"
+// @Success 200 {object} models.StoreCreditEntry
+// @Router /admin/users/{user_id}/store-credit/void [post]
+func VoidStoreCredit(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	var body models.BodyStoredValueVoidRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	body.Reason = strings.TrimSpace(body.Reason)
+	if body.Amount < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "amount cannot be negative"})
+	}
+	if body.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A reason is required"})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	var entry models.StoreCreditEntry
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+		var err error
+		if entry, err = module.VoidStoreCredit(tx, user.ID, body.Amount, body.Reason, parseActor(actorID)); err != nil {
+			return err
+		}
+		audit := module.NewAuditLog(actorID, "storecredit.void", "user", user.ID.String(), nil, body)
+		middleware.AuditRequest(c, &audit)
+		return module.RecordAudit(tx, audit)
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	case errors.Is(err, module.ErrInsufficientCredit):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "The user does not have that much store credit"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to void store credit"})
+	}
+	return c.Status(fiber.StatusOK).JSON(entry)
+}
+
+var errOrderNotFound = errors.New("order not found")
+
+// parseActor returns the acting user's ID, or nil for API keys.
+func parseActor(actorID string) *uuid.UUID {
+	if id, err := uuid.Parse(actorID); err == nil {
+		return &id
+	}
+	return nil
+}
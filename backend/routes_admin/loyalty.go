@@ -0,0 +1,257 @@
+package routes_admin
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/middleware"
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetLoyaltyRules godoc
+// @Summary List loyalty earn rules
+// @Tags loyalty
+// @Produce json
+// @Success 200 {array} models.LoyaltyRule
+// @Router /admin/loyalty/rules [get]
+func GetLoyaltyRules(c *fiber.Ctx) error {
+	var rules []models.LoyaltyRule
+	if err := db.DB.Order("id").Find(&rules).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch loyalty rules"})
+	}
+	return c.Status(fiber.StatusOK).JSON(rules)
+}
+
+// CreateLoyaltyRule godoc
+// @Summary Create a loyalty earn rule
+// @Description A category multiplier, or with starts_at and ends_at a bonus campaign. An item earns with the largest multiplier that matches it.
+// @Tags loyalty
+// @Accept json
+// @Produce json
+// @Param request body models.BodyLoyaltyRuleRequest true "Rule"
+// @Success 201 {object} models.LoyaltyRule
+// @Router /admin/loyalty/rules [post]
+func CreateLoyaltyRule(c *fiber.Ctx) error {
+	var body models.BodyLoyaltyRuleRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	if err := validateLoyaltyRule(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	rule := models.LoyaltyRule{}
+	applyLoyaltyRule(&rule, &body)
+	actorID, _ := c.Locals("userid").(string)
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&rule).Error; err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(actorID, "loyalty.rule_create", "loyalty_rule", strconv.Itoa(int(rule.ID)), nil, rule)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create loyalty rule"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(rule)
+}
+
+// UpdateLoyaltyRule godoc
+// @Summary Replace a loyalty earn rule
+// @Description Orders already credited keep their points
+// @Tags loyalty
+// @Accept json
+// @Produce json
+// @Param rule_id path int true "Rule ID"
+// @Param request body models.BodyLoyaltyRuleRequest true "Rule"
+// @Success 200 {object} models.LoyaltyRule
+// @Router /admin/loyalty/rules/{rule_id} [put]
+func UpdateLoyaltyRule(c *fiber.Ctx) error {
+	ruleID, err := c.ParamsInt("rule_id")
+	if err != nil || ruleID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid rule ID"})
+	}
+	var body models.BodyLoyaltyRuleRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	if err := validateLoyaltyRule(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var rule models.LoyaltyRule
+	actorID, _ := c.Locals("userid").(string)
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&rule, ruleID).Error; err != nil {
+			return err
+		}
+		before := rule
+		applyLoyaltyRule(&rule, &body)
+		if err := tx.Save(&rule).Error; err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(actorID, "loyalty.rule_update", "loyalty_rule", strconv.Itoa(int(rule.ID)), before, rule)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Loyalty rule not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update loyalty rule"})
+	}
+	return c.Status(fiber.StatusOK).JSON(rule)
+}
+
+// DeleteLoyaltyRule godoc
+// @Summary Delete a loyalty earn rule
+// @Tags loyalty
+// @Produce json
+// @Param rule_id path int true "Rule ID"
+// @Success 200 {object} models.MessageResponse
+// @Router /admin/loyalty/rules/{rule_id} [delete]
+func DeleteLoyaltyRule(c *fiber.Ctx) error {
+	ruleID, err := c.ParamsInt("rule_id")
+	if err != nil || ruleID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid rule ID"})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var rule models.LoyaltyRule
+		if err := tx.First(&rule, ruleID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&rule).Error; err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(actorID, "loyalty.rule_delete", "loyalty_rule", strconv.Itoa(int(rule.ID)), rule, nil)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Loyalty rule not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete loyalty rule"})
+	}
+	return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
+		Message: "Loyalty rule deleted",
+	})
+}
+
+// GetUserLoyalty godoc
+// @Summary A user's loyalty points
+// @Tags loyalty
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param limit query int false "Page size (default 50)"
+// @Param page query int false "Page number (default 1)"
+// @Success 200 {object} models.LoyaltyResponse
+// @Router /admin/users/{user_id}/loyalty [get]
+func GetUserLoyalty(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	limit := c.QueryInt("limit", 50)
+	page := c.QueryInt("page", 1)
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	resp, err := module.LoyaltySummary(db.DB, userID, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch points"})
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// AdjustUserLoyalty godoc
+// @Summary Add or remove a user's points
+// @Description Negative points remove, but not below a zero balance. The reason is shown in the user's history.
+// @Tags loyalty
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param request body models.BodyLoyaltyAdjustRequest true "Points and reason"
+// @Success 200 {object} models.LoyaltyEntry
+// @Router /admin/users/{user_id}/loyalty/adjust [post]
+func AdjustUserLoyalty(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	var body models.BodyLoyaltyAdjustRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	body.Reason = strings.TrimSpace(body.Reason)
+	if body.Points == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Points cannot be zero"})
+	}
+	if body.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A reason is required"})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	var entry models.LoyaltyEntry
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+		var err error
+		if entry, err = module.AdjustPoints(tx, user.ID, body.Points, body.Reason, parseActor(actorID)); err != nil {
+			return err
+		}
+		audit := module.NewAuditLog(actorID, "loyalty.adjust", "user", user.ID.String(), nil, body)
+		middleware.AuditRequest(c, &audit)
+		return module.RecordAudit(tx, audit)
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	case errors.Is(err, module.ErrInsufficientPoints):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "The user does not have that many points"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to adjust points"})
+	}
+	return c.Status(fiber.StatusOK).JSON(entry)
+}
+
+func validateLoyaltyRule(body *models.BodyLoyaltyRuleRequest) error {
+	body.Name = strings.TrimSpace(body.Name)
+	body.Category = strings.TrimSpace(body.Category)
+	if body.Name == "" {
+		return errors.New("name is required")
+	}
+	if body.Multiplier <= 0 || body.Multiplier > 100 {
+		return errors.New("multiplier must be above 0 and at most 100")
+	}
+	if body.StartsAt != nil && body.EndsAt != nil && !body.EndsAt.After(*body.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+func applyLoyaltyRule(rule *models.LoyaltyRule, body *models.BodyLoyaltyRuleRequest) {
+	rule.Name = body.Name
+	rule.Category = body.Category
+	rule.Multiplier = body.Multiplier
+	rule.StartsAt = body.StartsAt
+	rule.EndsAt = body.EndsAt
+	rule.IsActive = body.IsActive
+}
@@ -0,0 +1,56 @@
+package routes_admin
+
+import (
+	"errors"
+	"time"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/middleware"
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+	"Bakery_Pos/storage"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// GetOrderSlip godoc
+// @Summary Get a signed link to an order's payment slip
+// @Description Staff access to any order's slip. The link expires after 10 minutes and every call is recorded in the audit log.
+// @Tags Order
+// @Produce json
+// @Param order_id path string true "Order ID"
+// @Success 200 {object} models.OrderSlipResponse
+// @Router /admin/orders/{order_id}/slip [get]
+func GetOrderSlip(c *fiber.Ctx) error {
+	orderID := c.Params("order_id")
+
+	var order models.Order
+	if err := db.DB.Where("id = ?", orderID).First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch order"})
+	}
+
+	slipURL, err := module.SignSlipURL(c.UserContext(), db.Storage, order.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Slip not found"})
+	}
+	if err != nil {
+		return c.Status(storage.HTTPStatus(err)).JSON(fiber.Map{"error": "Failed to sign slip URL"})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	entry := module.NewAuditLog(actorID, "order.slip_view", "order", order.ID, nil, nil)
+	middleware.AuditRequest(c, &entry)
+	if err := module.RecordAudit(db.DB, entry); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record audit log"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.OrderSlipResponse{
+		OrderID:   order.ID,
+		SlipURL:   slipURL,
+		ExpiresAt: time.Now().Add(module.SlipURLTTL),
+	})
+}
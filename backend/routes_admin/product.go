@@ -3,8 +3,11 @@ package routes_admin
 import (
 	"Bakery_Pos/db"
 	"Bakery_Pos/models"
-	"fmt"
+	"Bakery_Pos/module"
+	"Bakery_Pos/storage"
+	"errors"
 	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -17,6 +20,7 @@ import (
 // @Produce json
 // @Param request body models.BodyProductRequest true "Product data"
 // @Param images_amount query int false "Number of images to create and get upload URLs for"
+// @Param content_type query string false "Image type to upload: image/png (default), image/jpeg or image/webp"
 // @Success 201 {object} models.ProductResponse
 // @Router /products [post]
 func CreateProduct(c *fiber.Ctx) error {
@@ -36,8 +40,24 @@ func CreateProduct(c *fiber.Ctx) error {
 		Stock:       req.Stock,
 		IsActive:    req.IsActive,
 	}
+	if err := applyProductInfo(&product, req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := applyProductCodes(&product, req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
 	if err := db.DB.Create(&product).Error; err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Name, SKU, barcode or PLU already in use",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create product",
 		})
@@ -52,32 +72,13 @@ func CreateProduct(c *fiber.Ctx) error {
 		})
 	}
 
-	var images []models.Image
 	var imageResponses []models.ImageResponse
 
 	if imagesAmount > 0 {
-		for i := 1; i <= imagesAmount; i++ {
-			fileName := fmt.Sprintf("%d-%d.png", product.ID, i)
-			filePath := fmt.Sprintf("products/%d/%s", product.ID, fileName)
-			fmt.Println(filePath)
-			signedURL, publicURL, err := db.Storage.GenerateUploadURL("product-images", filePath)
-			if err != nil {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-			}
-			image := models.Image{
-				ProductID: product.ID,
-				FilePath:  filePath,
-				PublicURL: &publicURL,
-			}
-			if err := db.DB.Create(&image).Error; err != nil {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-			}
-			imgResp := image.ToResponse()
-			imgResp.UploadURL = &signedURL
-			imageResponses = append(imageResponses, imgResp)
-			images = append(images, image)
+		imageResponses, err = createImageUploads(c.UserContext(), db.DB, product.ID, imagesAmount, 0, c.Query("content_type", "image/png"), true)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
-		product.Images = images
 	}
 
 	resp := product.ToResponse()
@@ -102,7 +103,7 @@ func UpdateProduct(c *fiber.Ctx) error {
 	id := c.Params("id")
 
 	var product models.Product
-	if err := db.DB.Preload("Images").First(&product, id).Error; err != nil {
+	if err := db.DB.Preload("Images", models.ImagesByPosition).First(&product, id).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Product not found",
 		})
@@ -121,8 +122,24 @@ func UpdateProduct(c *fiber.Ctx) error {
 	product.Price = body.Price
 	product.Stock = body.Stock
 	product.IsActive = body.IsActive
+	if err := applyProductInfo(&product, body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := applyProductCodes(&product, body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
 	if err := db.DB.Save(&product).Error; err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Name, SKU, barcode or PLU already in use",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update product",
 		})
@@ -156,6 +173,7 @@ func DeleteProduct(c *fiber.Ctx) error {
 // @Produce json
 // @Param id path int true "Product ID"
 // @Param image_amount query int true "Number of images to create and get upload URLs for"
+// @Param content_type query string false "Image type to upload: image/png (default), image/jpeg or image/webp"
 // @Success 200 {object} models.ImagesArrayResponse
 // @Router /products/{id}/images [post]
 func UploadImagesProduct(c *fiber.Ctx) error {
@@ -180,26 +198,9 @@ func UploadImagesProduct(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete old images"})
 	}
 
-	var results []models.ImageResponse
-
-	for i := 1; i <= imageAmount; i++ {
-		fileName := fmt.Sprintf("%d-%d.png", product.ID, i)
-		filePath := fmt.Sprintf("products/%d/%s", product.ID, fileName)
-		signedURL, publicURL, err := db.Storage.GenerateUploadURL("product-images", filePath)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-		}
-		image := models.Image{
-			ProductID: product.ID,
-			FilePath:  filePath,
-			PublicURL: &publicURL,
-		}
-		if err := db.DB.Create(&image).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-		}
-		imgResp := image.ToResponse()
-		imgResp.UploadURL = &signedURL
-		results = append(results, imgResp)
+	results, err := createImageUploads(c.UserContext(), db.DB, product.ID, imageAmount, 0, c.Query("content_type", "image/png"), true)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	return c.Status(fiber.StatusOK).JSON(models.ImagesArrayResponse{
@@ -233,7 +234,7 @@ func DeleteImagesByID(c *fiber.Ctx) error {
 	}
 
 	var images []models.Image
-	if err := db.DB.Where("product_id = ? && id IN ?", productID, body.IDs).Find(&images).Error; err != nil {
+	if err := db.DB.Where("product_id = ? AND id IN ?", productID, body.IDs).Find(&images).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
@@ -242,12 +243,18 @@ func DeleteImagesByID(c *fiber.Ctx) error {
 	}
 
 	for _, img := range images {
-		if err := db.Storage.RemoveFile("product-images", img.FilePath); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		for _, filePath := range []string{img.FilePath, img.ThumbnailPath, img.MediumPath} {
+			if filePath == "" {
+				continue
+			}
+			err := db.Storage.RemoveFile(c.UserContext(), module.ProductImageBucket, filePath)
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return c.Status(storage.HTTPStatus(err)).JSON(fiber.Map{"error": err.Error()})
+			}
 		}
 	}
 
-	if err := db.DB.Where("id IN ?", body.IDs).Delete(&models.Image{}).Error; err != nil {
+	if err := db.DB.Where("product_id = ? AND id IN ?", productID, body.IDs).Delete(&models.Image{}).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
@@ -255,3 +262,63 @@ func DeleteImagesByID(c *fiber.Ctx) error {
 		Message: "Images deleted successfully",
 	})
 }
+
+// applyProductInfo copies allergen, dietary, nutrition and early access details from the request
+func applyProductInfo(product *models.Product, req models.BodyProductRequest) error {
+	allergens, err := models.ParseAllergens(req.Allergens)
+	if err != nil {
+		return err
+	}
+	dietary, err := models.ParseDietaryLabels(req.Dietary)
+	if err != nil {
+		return err
+	}
+	if err := models.CheckDietaryConflicts(allergens, dietary); err != nil {
+		return err
+	}
+
+	product.Allergens = allergens
+	product.Dietary = dietary
+	product.Ingredients = req.Ingredients
+	product.ShelfLife = req.ShelfLife
+	product.EarlyAccessUntil = req.EarlyAccessUntil
+	product.Nutrition = models.NutritionFacts{}
+	if req.Nutrition != nil {
+		product.Nutrition = *req.Nutrition
+	}
+	return nil
+}
+
+// applyProductCodes validates and copies SKU, barcode and PLU. Empty strings clear a code.
+func applyProductCodes(product *models.Product, req models.BodyProductRequest) error {
+	product.SKU = nil
+	if req.SKU != nil {
+		if sku := strings.TrimSpace(*req.SKU); sku != "" {
+			product.SKU = &sku
+		}
+	}
+
+	product.Barcode = nil
+	if req.Barcode != nil && strings.TrimSpace(*req.Barcode) != "" {
+		gtin, err := module.NormalizeGTIN(strings.TrimSpace(*req.Barcode))
+		if err != nil {
+			return err
+		}
+		if module.IsInStoreBarcode(gtin) {
+			return errors.New("barcodes starting with 2 are reserved for in-store labels, set a PLU instead")
+		}
+		product.Barcode = &gtin
+	}
+
+	product.PLU = nil
+	if req.PLU != nil && strings.TrimSpace(*req.PLU) != "" {
+		plu := strings.TrimSpace(*req.PLU)
+		if !module.ValidPLU(plu) {
+			return errors.New("PLU must be 5 digits")
+		}
+		product.PLU = &plu
+	}
+
+	product.SoldByWeight = req.SoldByWeight
+	return nil
+}
@@ -0,0 +1,191 @@
+package routes_admin
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/middleware"
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// BulkUpdateProducts godoc
+// @Summary Bulk adjust product price, stock or active state
+// @Description Changing stock requires inventory.write, price and is_active require products.write. Select products by ids, category and/or search query (or all=true) and apply one operation: set, increase or decrease price/stock by an absolute or percent amount, or set/toggle is_active. Use dry_run=true to preview. Every applied change is written to the audit log.
+// @Tags product
+// @Accept json
+// @Produce json
+// @Param request body models.BodyBulkProductRequest true "Selector and operation"
+// @Success 200 {object} models.BulkProductResponse
+// @Router /admin/products/bulk [post]
+func BulkUpdateProducts(c *fiber.Ctx) error {
+	var body models.BodyBulkProductRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	sel := body.Selector
+	if len(sel.IDs) == 0 && sel.Category == "" && sel.Query == "" && !sel.All {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Selector is empty, pass ids, category, q or all=true"})
+	}
+	if err := validateBulkOperation(body.Operation); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	// stock is inventory, everything else changes the catalogue
+	required := models.PermProductsWrite
+	if body.Operation.Field == "stock" {
+		required = models.PermInventoryWrite
+	}
+	if !middleware.HasPermission(c, required) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied: missing permission " + required})
+	}
+
+	query := db.DB.Order("id ASC")
+	if len(sel.IDs) > 0 {
+		query = query.Where("id IN ?", sel.IDs)
+	}
+	if sel.Category != "" {
+		query = query.Where("tag = ?", sel.Category)
+	}
+	if sel.Query != "" {
+		like := fmt.Sprintf("%%%s%%", sel.Query)
+		query = query.Where("name LIKE ? OR tag LIKE ?", like, like)
+	}
+
+	var products []models.Product
+	if err := query.Find(&products).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch products"})
+	}
+
+	resp := models.BulkProductResponse{
+		DryRun:  body.DryRun,
+		Matched: len(products),
+		Changes: []models.BulkProductChange{},
+	}
+
+	var changed []*models.Product
+	for i := range products {
+		p := &products[i]
+		change, ok := applyBulkOperation(p, body.Operation)
+		if !ok {
+			continue
+		}
+		resp.Changes = append(resp.Changes, change)
+		changed = append(changed, p)
+	}
+	resp.Changed = len(changed)
+
+	if body.DryRun || len(changed) == 0 {
+		return c.Status(fiber.StatusOK).JSON(resp)
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	column := body.Operation.Field
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		entries := make([]models.AuditLog, 0, len(changed))
+		for i, p := range changed {
+			change := resp.Changes[i]
+			if err := tx.Model(p).Update(column, change.To).Error; err != nil {
+				return err
+			}
+			entry := module.NewAuditLog(actorID, "product.bulk_update", "product", strconv.FormatUint(uint64(p.ID), 10),
+				fiber.Map{column: change.From}, fiber.Map{column: change.To})
+			middleware.AuditRequest(c, &entry)
+			entries = append(entries, entry)
+		}
+		return module.RecordAudit(tx, entries...)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update products"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+func validateBulkOperation(op models.BulkProductOperation) error {
+	switch op.Field {
+	case "price", "stock":
+		switch op.Type {
+		case "set", "increase", "decrease":
+		default:
+			return fmt.Errorf("operation type %q is not supported for %s", op.Type, op.Field)
+		}
+		if op.Unit != "" && op.Unit != "absolute" && op.Unit != "percent" {
+			return fmt.Errorf("unit must be absolute or percent")
+		}
+		if op.Type == "set" && op.Unit == "percent" {
+			return fmt.Errorf("set does not support percent")
+		}
+		if op.Amount < 0 {
+			return fmt.Errorf("amount must not be negative")
+		}
+	case "is_active":
+		switch op.Type {
+		case "toggle":
+		case "set":
+			if op.Active == nil {
+				return fmt.Errorf("active is required to set is_active")
+			}
+		default:
+			return fmt.Errorf("operation type %q is not supported for is_active", op.Type)
+		}
+	default:
+		return fmt.Errorf("field must be price, stock or is_active")
+	}
+	return nil
+}
+
+// applyBulkOperation mutates p and reports the change, or false when the value stays the same.
+// Decreases are clamped at zero; prices are rounded to satang and stock to whole units.
+func applyBulkOperation(p *models.Product, op models.BulkProductOperation) (models.BulkProductChange, bool) {
+	change := models.BulkProductChange{ProductID: p.ID, Name: p.Name, Field: op.Field}
+
+	adjust := func(current float64) float64 {
+		delta := op.Amount
+		if op.Unit == "percent" {
+			delta = current * op.Amount / 100
+		}
+		switch op.Type {
+		case "set":
+			return op.Amount
+		case "increase":
+			return current + delta
+		default:
+			return math.Max(current-delta, 0)
+		}
+	}
+
+	switch op.Field {
+	case "price":
+		next := math.Round(adjust(p.Price)*100) / 100
+		if next == p.Price {
+			return change, false
+		}
+		change.From, change.To = p.Price, next
+		p.Price = next
+	case "stock":
+		next := int(math.Round(adjust(float64(p.Stock))))
+		if next == p.Stock {
+			return change, false
+		}
+		change.From, change.To = p.Stock, next
+		p.Stock = next
+	case "is_active":
+		next := !p.IsActive
+		if op.Type == "set" {
+			next = *op.Active
+		}
+		if next == p.IsActive {
+			return change, false
+		}
+		change.From, change.To = p.IsActive, next
+		p.IsActive = next
+	}
+	return change, true
+}
@@ -0,0 +1,318 @@
+package routes_admin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+	"Bakery_Pos/storage"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// createImageUploads creates pending image rows from position onwards and
+// returns them with signed upload URLs. The first row becomes primary when
+// primary is set.
+func createImageUploads(ctx context.Context, tx *gorm.DB, productID uint, amount, position int, contentType string, primary bool) ([]models.ImageResponse, error) {
+	ext, ok := module.ImageExtensions[contentType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported content_type %q", contentType)
+	}
+
+	results := make([]models.ImageResponse, 0, amount)
+	for i := 0; i < amount; i++ {
+		filePath := fmt.Sprintf("products/%d/%d-%s%s", productID, productID, uuid.New().String()[:8], ext)
+		signedURL, publicURL, err := db.Storage.GenerateUploadURL(ctx, module.ProductImageBucket, filePath)
+		if err != nil {
+			return nil, err
+		}
+		image := models.Image{
+			ProductID:   productID,
+			FilePath:    filePath,
+			PublicURL:   &publicURL,
+			Position:    position + i,
+			IsPrimary:   primary && i == 0,
+			Status:      models.ImageStatusPending,
+			ContentType: contentType,
+		}
+		if err := tx.Create(&image).Error; err != nil {
+			return nil, err
+		}
+		imgResp := image.ToResponse()
+		imgResp.UploadURL = &signedURL
+		results = append(results, imgResp)
+	}
+	return results, nil
+}
+
+func parseImageParams(c *fiber.Ctx) (productID uint, imageID uint, err error) {
+	pid, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("Invalid product ID")
+	}
+	if c.Params("image_id") == "" {
+		return uint(pid), 0, nil
+	}
+	iid, err := strconv.ParseUint(c.Params("image_id"), 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("Invalid image ID")
+	}
+	return uint(pid), uint(iid), nil
+}
+
+// AppendImagesProduct godoc
+// @Summary Append images to a product
+// @Description Create upload URLs for additional images after the existing ones, without touching them. Call the confirm endpoint after each upload.
+// @Tags product-images
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param image_amount query int true "Number of images to add"
+// @Param content_type query string false "image/png (default), image/jpeg or image/webp"
+// @Success 200 {object} models.ImagesArrayResponse
+// @Router /products/{id}/images/append [post]
+func AppendImagesProduct(c *fiber.Ctx) error {
+	productID, _, err := parseImageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	imageAmount := c.QueryInt("image_amount", 0)
+	if imageAmount <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid image_amount query parameter"})
+	}
+
+	var product models.Product
+	if err := db.DB.First(&product, productID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Product not found"})
+	}
+
+	var stats struct {
+		Count       int64
+		MaxPosition int
+		Primaries   int64
+	}
+	if err := db.DB.Model(&models.Image{}).
+		Select("COUNT(*) as count, COALESCE(MAX(position), -1) as max_position, COUNT(*) FILTER (WHERE is_primary) as primaries").
+		Where("product_id = ?", productID).
+		Scan(&stats).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load images"})
+	}
+
+	var results []models.ImageResponse
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		results, err = createImageUploads(c.UserContext(), tx, productID, imageAmount, stats.MaxPosition+1, c.Query("content_type", "image/png"), stats.Primaries == 0)
+		return err
+	})
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.ImagesArrayResponse{
+		Images: results,
+	})
+}
+
+// ReorderImagesProduct godoc
+// @Summary Reorder product images
+// @Description Set the display order of all images of a product. ids must list every image of the product exactly once.
+// @Tags product-images
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param request body models.ImageIDsRequest true "Image IDs in display order"
+// @Success 200 {object} models.ImagesArrayResponse
+// @Router /products/{id}/images/order [put]
+func ReorderImagesProduct(c *fiber.Ctx) error {
+	productID, _, err := parseImageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var body models.ImageIDsRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	var images []models.Image
+	if err := db.DB.Where("product_id = ?", productID).Find(&images).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load images"})
+	}
+
+	byID := make(map[uint]*models.Image, len(images))
+	for i := range images {
+		byID[images[i].ID] = &images[i]
+	}
+	if len(body.IDs) != len(images) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ids must list every image of the product"})
+	}
+	for pos, id := range body.IDs {
+		img, ok := byID[id]
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Image %d does not belong to this product or is listed twice", id)})
+		}
+		img.Position = pos
+		delete(byID, id)
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range images {
+			if err := tx.Model(&images[i]).Update("position", images[i].Position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reorder images"})
+	}
+
+	if err := db.DB.Scopes(models.ImagesByPosition).Where("product_id = ?", productID).Find(&images).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load images"})
+	}
+	results := make([]models.ImageResponse, len(images))
+	for i := range images {
+		results[i] = images[i].ToResponse()
+	}
+	return c.Status(fiber.StatusOK).JSON(models.ImagesArrayResponse{
+		Images: results,
+	})
+}
+
+// UpdateImageProduct godoc
+// @Summary Update image alt text or primary flag
+// @Description Setting is_primary=true clears the flag on the product's other images
+// @Tags product-images
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param image_id path int true "Image ID"
+// @Param request body models.BodyImageUpdateRequest true "Fields to update"
+// @Success 200 {object} models.ImageResponse
+// @Router /products/{id}/images/{image_id} [put]
+func UpdateImageProduct(c *fiber.Ctx) error {
+	productID, imageID, err := parseImageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var body models.BodyImageUpdateRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	var image models.Image
+	if err := db.DB.Where("id = ? AND product_id = ?", imageID, productID).First(&image).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+
+	if body.AltText != nil {
+		image.AltText = strings.TrimSpace(*body.AltText)
+	}
+	if body.IsPrimary != nil {
+		image.IsPrimary = *body.IsPrimary
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if image.IsPrimary {
+			if err := tx.Model(&models.Image{}).
+				Where("product_id = ? AND id <> ?", productID, image.ID).
+				Update("is_primary", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(&image).Error
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update image"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(image.ToResponse())
+}
+
+// ConfirmImageProduct godoc
+// @Summary Confirm an uploaded product image
+// @Description Call after uploading to the signed URL. The file is checked for size (max 5 MB and 40 megapixels) and content type (png, jpeg or webp, matching the requested type), then thumbnail and medium JPEG renditions are generated. Invalid files are removed from storage.
+// @Tags product-images
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param image_id path int true "Image ID"
+// @Success 200 {object} models.ImageResponse
+// @Failure 422 {object} map[string]string
+// @Router /products/{id}/images/{image_id}/confirm [post]
+func ConfirmImageProduct(c *fiber.Ctx) error {
+	productID, imageID, err := parseImageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var image models.Image
+	if err := db.DB.Where("id = ? AND product_id = ?", imageID, productID).First(&image).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	}
+
+	// check the size before reading the file into memory
+	var data []byte
+	info, err := db.Storage.Stat(c.UserContext(), module.ProductImageBucket, image.FilePath)
+	if err == nil && info.Size <= module.MaxImageSize {
+		data, err = db.Storage.Download(c.UserContext(), module.ProductImageBucket, image.FilePath)
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Uploaded file not found"})
+	}
+	if err != nil {
+		return c.Status(storage.HTTPStatus(err)).JSON(fiber.Map{"error": "Failed to download uploaded file"})
+	}
+
+	var renditions *module.ImageRenditions
+	if info.Size > module.MaxImageSize {
+		err = fmt.Errorf("image is larger than %d MB", module.MaxImageSize>>20)
+	} else {
+		renditions, err = module.ProcessImage(data)
+	}
+	if err == nil && module.ImageExtensions[renditions.ContentType] != path.Ext(image.FilePath) {
+		err = fmt.Errorf("uploaded file is %s, expected %s", renditions.ContentType, image.ContentType)
+	}
+	if err != nil {
+		if rmErr := db.Storage.RemoveFile(c.UserContext(), module.ProductImageBucket, image.FilePath); rmErr != nil {
+			log.Printf("Failed to remove rejected image %s: %v", image.FilePath, rmErr)
+		}
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	base := strings.TrimSuffix(image.FilePath, path.Ext(image.FilePath))
+	thumbPath := base + "_thumb.jpg"
+	mediumPath := base + "_medium.jpg"
+	if err := db.Storage.Upload(c.UserContext(), module.ProductImageBucket, thumbPath, bytes.NewReader(renditions.Thumbnail), int64(len(renditions.Thumbnail)), "image/jpeg"); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to store thumbnail"})
+	}
+	if err := db.Storage.Upload(c.UserContext(), module.ProductImageBucket, mediumPath, bytes.NewReader(renditions.Medium), int64(len(renditions.Medium)), "image/jpeg"); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to store medium image"})
+	}
+
+	thumbURL := db.Storage.GetPublicURL(module.ProductImageBucket, thumbPath)
+	mediumURL := db.Storage.GetPublicURL(module.ProductImageBucket, mediumPath)
+	image.ContentType = renditions.ContentType
+	image.Size = int64(len(data))
+	image.Status = models.ImageStatusReady
+	image.ThumbnailPath = thumbPath
+	image.ThumbnailURL = &thumbURL
+	image.MediumPath = mediumPath
+	image.MediumURL = &mediumURL
+
+	if err := db.DB.Save(&image).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update image"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(image.ToResponse())
+}
@@ -0,0 +1,455 @@
+package routes_admin
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+var productFileColumns = []string{"name", "description", "category", "price", "stock", "active"}
+
+// header aliases so files exported from the API (json names) can be imported back
+var productColumnAliases = map[string]string{
+	"name":        "name",
+	"description": "description",
+	"detail":      "description",
+	"category":    "category",
+	"tag":         "category",
+	"price":       "price",
+	"stock":       "stock",
+	"quantity":    "stock",
+	"active":      "active",
+	"is_active":   "active",
+}
+
+type productImportRow struct {
+	line        int
+	name        string
+	description string
+	category    string
+	price       float64
+	stock       int
+	active      bool
+	errors      []string
+
+	// description and active are optional columns; a file without them
+	// leaves those fields of existing products as they are
+	hasDescription bool
+	hasActive      bool
+}
+
+// ImportProducts godoc
+// @Summary Bulk import products from CSV or XLSX
+// @Description Upload a CSV or XLSX file with columns name, description, category, price, stock, active. Products are matched by name; description and active are optional and left unchanged on existing products when their column is missing. Use ?dry_run=true to validate and preview the create/update/skip diff without saving. A real import is applied in a single transaction and is rejected when any row is invalid.
+// @Tags product
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV or XLSX file"
+// @Param dry_run query bool false "Validate and preview only"
+// @Param format query string false "File format (csv|xlsx), detected from the file name when omitted"
+// @Success 200 {object} models.ProductImportResponse
+// @Failure 422 {object} models.ProductImportResponse
+// @Router /admin/products/import [post]
+func ImportProducts(c *fiber.Ctx) error {
+	dryRun := c.QueryBool("dry_run", false)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Missing file"})
+	}
+
+	format := strings.ToLower(c.Query("format", ""))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+	}
+	if format != "csv" && format != "xlsx" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unsupported file format, use csv or xlsx"})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Failed to read file"})
+	}
+	defer file.Close()
+
+	var records [][]string
+	if format == "csv" {
+		records, err = readCSVRecords(file)
+	} else {
+		records, err = readXLSXRecords(file)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	rows, err := parseProductRecords(records)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// match existing products by name, including soft deleted ones since the
+	// unique index on name still covers them
+	names := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if row.name != "" {
+			names = append(names, row.name)
+		}
+	}
+	var existing []models.Product
+	if len(names) > 0 {
+		if err := db.DB.Unscoped().Where("name IN ?", names).Find(&existing).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load products"})
+		}
+	}
+	byName := make(map[string]*models.Product, len(existing))
+	for i := range existing {
+		byName[existing[i].Name] = &existing[i]
+	}
+
+	resp := models.ProductImportResponse{
+		DryRun: dryRun,
+		Rows:   make([]models.ProductImportRowResult, 0, len(rows)),
+	}
+
+	for _, row := range rows {
+		result := models.ProductImportRowResult{
+			Line:   row.line,
+			Name:   row.name,
+			Errors: row.errors,
+		}
+
+		if len(row.errors) > 0 {
+			result.Action = "error"
+			resp.Summary.Errors++
+			resp.Rows = append(resp.Rows, result)
+			continue
+		}
+
+		product, found := byName[row.name]
+		switch {
+		case !found || product.DeletedAt.Valid:
+			result.Action = "create"
+			resp.Summary.Create++
+		default:
+			result.ProductID = product.ID
+			result.Changes = diffImportRow(product, row)
+			if len(result.Changes) == 0 {
+				result.Action = "skip"
+				resp.Summary.Skip++
+			} else {
+				result.Action = "update"
+				resp.Summary.Update++
+			}
+		}
+		resp.Rows = append(resp.Rows, result)
+	}
+
+	if resp.Summary.Errors > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(resp)
+	}
+	if dryRun {
+		return c.Status(fiber.StatusOK).JSON(resp)
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		for i, row := range rows {
+			product, found := byName[row.name]
+			switch resp.Rows[i].Action {
+			case "create":
+				if found {
+					// restore the soft deleted product instead of violating the unique name
+					applyImportRow(product, row)
+					product.IsActive = row.active
+					product.DeletedAt = gorm.DeletedAt{}
+					if err := tx.Unscoped().Save(product).Error; err != nil {
+						return fmt.Errorf("line %d: failed to restore product", row.line)
+					}
+				} else {
+					product = &models.Product{}
+					applyImportRow(product, row)
+					if err := tx.Create(product).Error; err != nil {
+						return fmt.Errorf("line %d: failed to create product", row.line)
+					}
+					// is_active has a database default, so a false value is skipped on insert
+					if !row.active {
+						if err := tx.Model(product).Update("is_active", false).Error; err != nil {
+							return fmt.Errorf("line %d: failed to create product", row.line)
+						}
+					}
+				}
+				resp.Rows[i].ProductID = product.ID
+			case "update":
+				applyImportRow(product, row)
+				if err := tx.Save(product).Error; err != nil {
+					return fmt.Errorf("line %d: failed to update product", row.line)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	resp.Applied = true
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// ExportProducts godoc
+// @Summary Export products to CSV or XLSX
+// @Description Download all products in the same column layout accepted by the import endpoint
+// @Tags product
+// @Produce octet-stream
+// @Param format query string false "File format (csv|xlsx)" default(csv)
+// @Success 200 {file} file
+// @Router /admin/products/export [get]
+func ExportProducts(c *fiber.Ctx) error {
+	format := strings.ToLower(c.Query("format", "csv"))
+	if format != "csv" && format != "xlsx" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unsupported file format, use csv or xlsx"})
+	}
+
+	var products []models.Product
+	if err := db.DB.Order("name ASC").Find(&products).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch products"})
+	}
+
+	records := make([][]string, 0, len(products)+1)
+	records = append(records, productFileColumns)
+	for _, p := range products {
+		records = append(records, []string{
+			p.Name,
+			p.Description,
+			p.Tag,
+			strconv.FormatFloat(p.Price, 'f', -1, 64),
+			strconv.Itoa(p.Stock),
+			strconv.FormatBool(p.IsActive),
+		})
+	}
+
+	var buf bytes.Buffer
+	if format == "csv" {
+		w := csv.NewWriter(&buf)
+		if err := w.WriteAll(records); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to write csv"})
+		}
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	} else {
+		if err := writeXLSXRecords(&buf, records); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to write xlsx"})
+		}
+		c.Set(fiber.HeaderContentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	}
+
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"products.%s\"", format))
+	return c.Status(fiber.StatusOK).Send(buf.Bytes())
+}
+
+func readCSVRecords(r io.Reader) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %v", err)
+	}
+	// strip the UTF-8 BOM Excel adds to csv exports
+	if len(records) > 0 && len(records[0]) > 0 {
+		records[0][0] = strings.TrimPrefix(records[0][0], "\ufeff")
+	}
+	return records, nil
+}
+
+func readXLSXRecords(r io.Reader) ([][]string, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xlsx: %v", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errors.New("xlsx file has no sheets")
+	}
+	records, err := f.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid xlsx: %v", err)
+	}
+	return records, nil
+}
+
+func writeXLSXRecords(w io.Writer, records [][]string) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := f.GetSheetName(0)
+	for i, record := range records {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return err
+		}
+		values := make([]interface{}, len(record))
+		for j, v := range record {
+			values[j] = v
+		}
+		if err := f.SetSheetRow(sheet, cell, &values); err != nil {
+			return err
+		}
+	}
+	return f.Write(w)
+}
+
+// parseProductRecords maps the header row to known columns and validates each data row.
+// Line numbers are 1-based and count the header, matching what spreadsheet users see.
+func parseProductRecords(records [][]string) ([]productImportRow, error) {
+	if len(records) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	columns := make(map[string]int)
+	for i, h := range records[0] {
+		key := strings.ToLower(strings.TrimSpace(h))
+		if col, ok := productColumnAliases[key]; ok {
+			columns[col] = i
+		}
+	}
+	for _, col := range []string{"name", "category", "price", "stock"} {
+		if _, ok := columns[col]; !ok {
+			return nil, fmt.Errorf("missing required column %q", col)
+		}
+	}
+
+	cell := func(record []string, col string) string {
+		i, ok := columns[col]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []productImportRow
+	seen := make(map[string]int)
+	for i, record := range records[1:] {
+		line := i + 2
+		if isBlankRecord(record) {
+			continue
+		}
+
+		row := productImportRow{
+			line:        line,
+			name:        cell(record, "name"),
+			description: cell(record, "description"),
+			category:    cell(record, "category"),
+			active:      true,
+		}
+		_, row.hasDescription = columns["description"]
+		_, row.hasActive = columns["active"]
+
+		if row.name == "" {
+			row.errors = append(row.errors, "name is required")
+		} else if prev, ok := seen[row.name]; ok {
+			row.errors = append(row.errors, fmt.Sprintf("duplicate name, first seen on line %d", prev))
+		} else {
+			seen[row.name] = line
+		}
+		if row.category == "" {
+			row.errors = append(row.errors, "category is required")
+		}
+
+		price, err := strconv.ParseFloat(cell(record, "price"), 64)
+		if err != nil || math.IsNaN(price) || math.IsInf(price, 0) {
+			row.errors = append(row.errors, "price must be a number")
+		} else if price < 0 {
+			row.errors = append(row.errors, "price must not be negative")
+		}
+		row.price = price
+
+		stock, err := strconv.Atoi(cell(record, "stock"))
+		if err != nil {
+			row.errors = append(row.errors, "stock must be a whole number")
+		} else if stock < 0 {
+			row.errors = append(row.errors, "stock must not be negative")
+		}
+		row.stock = stock
+
+		if v := cell(record, "active"); v != "" {
+			active, ok := parseImportBool(v)
+			if !ok {
+				row.errors = append(row.errors, "active must be true or false")
+			}
+			row.active = active
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("file has no product rows")
+	}
+	return rows, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func parseImportBool(v string) (bool, bool) {
+	switch strings.ToLower(v) {
+	case "true", "yes", "y", "1":
+		return true, true
+	case "false", "no", "n", "0":
+		return false, true
+	}
+	return false, false
+}
+
+func diffImportRow(p *models.Product, row productImportRow) map[string]models.FieldChange {
+	changes := make(map[string]models.FieldChange)
+	if row.hasDescription && p.Description != row.description {
+		changes["description"] = models.FieldChange{From: p.Description, To: row.description}
+	}
+	if p.Tag != row.category {
+		changes["category"] = models.FieldChange{From: p.Tag, To: row.category}
+	}
+	if p.Price != row.price {
+		changes["price"] = models.FieldChange{From: p.Price, To: row.price}
+	}
+	if p.Stock != row.stock {
+		changes["stock"] = models.FieldChange{From: p.Stock, To: row.stock}
+	}
+	if row.hasActive && p.IsActive != row.active {
+		changes["active"] = models.FieldChange{From: p.IsActive, To: row.active}
+	}
+	return changes
+}
+
+func applyImportRow(p *models.Product, row productImportRow) {
+	p.Name = row.name
+	if row.hasDescription {
+		p.Description = row.description
+	}
+	p.Tag = row.category
+	p.Price = row.price
+	p.Stock = row.stock
+	if row.hasActive {
+		p.IsActive = row.active
+	}
+}
@@ -46,7 +46,7 @@ func GetTopProducts(c *fiber.Ctx) error {
 		Select("order_items.product_id, products.name, SUM(order_items.quantity) as total_sold, SUM(order_items.quantity * order_items.price) as revenue").
 		Joins("JOIN orders ON orders.id = order_items.order_id").
 		Joins("JOIN products ON products.id = order_items.product_id").
-		Where("orders.created_at >= ?", start).
+		Where("orders.created_at >= ? AND orders.status <> 'cancelled'", start).
 		Group("order_items.product_id, products.name").
 		Order("total_sold DESC").
 		Limit(limit).
@@ -90,7 +90,7 @@ func GetSalesByHour(c *fiber.Ctx) error {
 	var results []models.SalesByHourReport
 	err = db.DB.Table("orders").
 		Select("TO_CHAR(orders.created_at, 'HH24:00') as hour, SUM(orders.total) as total, COUNT(*) as orders").
-		Where("orders.created_at >= ? AND orders.created_at < ?", start, end).
+		Where("orders.created_at >= ? AND orders.created_at < ? AND orders.status <> 'cancelled'", start, end).
 		Group("hour").
 		Order("hour").
 		Scan(&results).Error
@@ -153,7 +153,7 @@ func GetSalesByDay(c *fiber.Ctx) error {
 
 	err := db.DB.Table("orders").
 		Select("TO_CHAR(created_at, 'YYYY-MM-DD') as date, SUM(total) as total, COUNT(*) as orders").
-		Where("created_at >= ? AND created_at < ?", start, end).
+		Where("created_at >= ? AND created_at < ? AND status <> 'cancelled'", start, end).
 		Group("date").
 		Order("date").
 		Scan(&results).Error
@@ -210,7 +210,8 @@ func GetProductSalesSummary(c *fiber.Ctx) error {
 	base := db.DB.Table("order_items").
 		Select("order_items.product_id as product_id, products.name as product_name, SUM(order_items.quantity) as total_quantity, SUM(order_items.quantity * order_items.price) as total_revenue").
 		Joins("JOIN orders ON orders.id = order_items.order_id").
-		Joins("JOIN products ON products.id = order_items.product_id")
+		Joins("JOIN products ON products.id = order_items.product_id").
+		Where("orders.status <> 'cancelled'")
 
 	if !start.IsZero() && !end.IsZero() {
 		base = base.Where("orders.created_at >= ? AND orders.created_at < ?", start, end)
@@ -224,7 +225,7 @@ func GetProductSalesSummary(c *fiber.Ctx) error {
 	var total int64
 	if limit > 0 {
 		// count distinct product ids matching filters
-		countQ := db.DB.Table("order_items").Joins("JOIN orders ON orders.id = order_items.order_id")
+		countQ := db.DB.Table("order_items").Joins("JOIN orders ON orders.id = order_items.order_id").Where("orders.status <> 'cancelled'")
 		if !start.IsZero() && !end.IsZero() {
 			countQ = countQ.Where("orders.created_at >= ? AND orders.created_at < ?", start, end)
 		} else if !start.IsZero() {
@@ -0,0 +1,180 @@
+package routes_admin
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/middleware"
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetShifts godoc
+// @Summary List cashier shifts
+// @Tags reports
+// @Produce json
+// @Param status query string false "open or closed"
+// @Param cashier_id query string false "Cashier user ID"
+// @Param limit query int false "Page size (default 50)"
+// @Param page query int false "Page number (default 1)"
+// @Success 200 {object} models.ShiftListResponse
+// @Router /reports/shifts [get]
+func GetShifts(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	page := c.QueryInt("page", 1)
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	query := db.DB.Model(&models.Shift{})
+	if v := c.Query("status"); v != "" {
+		if v != models.ShiftOpen && v != models.ShiftClosed {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "status must be open or closed"})
+		}
+		query = query.Where("status = ?", v)
+	}
+	if v := c.Query("cashier_id"); v != "" {
+		cashierID, err := uuid.Parse(v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid cashier ID"})
+		}
+		query = query.Where("cashier_id = ?", cashierID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to count shifts"})
+	}
+
+	shifts := []models.Shift{}
+	if err := query.Order("opened_at DESC, id DESC").Limit(limit).Offset((page - 1) * limit).Find(&shifts).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch shifts"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.ShiftListResponse{
+		Data:  shifts,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	})
+}
+
+// GetShiftReport godoc
+// @Summary Shift report
+// @Description Sales by tender, refunds, discounts and cash movements of one shift, with the expected cash and, once closed, the over/short
+// @Tags reports
+// @Produce json
+// @Param shift_id path int true "Shift ID"
+// @Success 200 {object} models.ShiftReport
+// @Router /reports/shifts/{shift_id} [get]
+func GetShiftReport(c *fiber.Ctx) error {
+	shiftID, err := c.ParamsInt("shift_id")
+	if err != nil || shiftID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid shift ID"})
+	}
+
+	var shift models.Shift
+	if err := db.DB.First(&shift, shiftID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Shift not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch shift"})
+	}
+
+	report, err := module.BuildShiftReport(db.DB, shift)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to build shift report"})
+	}
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// CloseShift godoc
+// @Summary Close a cashier's shift
+// @Description For a shift its cashier left open: a manager counts the drawer and closes it. The note should say why.
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param shift_id path int true "Shift ID"
+// @Param request body models.BodyCloseShiftRequest true "Counted cash and note"
+// @Success 200 {object} models.ShiftReport
+// @Router /reports/shifts/{shift_id}/close [post]
+func CloseShift(c *fiber.Ctx) error {
+	shiftID, err := c.ParamsInt("shift_id")
+	if err != nil || shiftID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid shift ID"})
+	}
+	var body models.BodyCloseShiftRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	body.Note = strings.TrimSpace(body.Note)
+	if body.CountedCash < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "counted_cash cannot be negative"})
+	}
+	if body.Note == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A note is required"})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	var report models.ShiftReport
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		before, err := module.LockOpenShift(tx, uint(shiftID))
+		if err != nil {
+			return err
+		}
+		closed, err := module.CloseShift(tx, before.ID, body.CountedCash, body.Note, parseActor(actorID))
+		if err != nil {
+			return err
+		}
+		if report, err = module.BuildShiftReport(tx, closed); err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(actorID, "shift.close", "shift", strconv.Itoa(int(closed.ID)), before, closed)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	switch {
+	case errors.Is(err, module.ErrNoOpenShift):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Shift not found"})
+	case errors.Is(err, module.ErrShiftClosed):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Shift is already closed"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to close shift"})
+	}
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// GetZReport godoc
+// @Summary End of day Z-report
+// @Description Every order of the day, online and at the counter, by tender, with each shift opened that day
+// @Tags reports
+// @Produce json
+// @Param date query string false "Date in YYYY-MM-DD format (default today)"
+// @Success 200 {object} models.ZReport
+// @Router /reports/z [get]
+func GetZReport(c *fiber.Ctx) error {
+	now := time.Now()
+	date := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if v := c.Query("date"); v != "" {
+		var err error
+		if date, err = time.ParseInLocation("2006-01-02", v, now.Location()); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid date format"})
+		}
+	}
+
+	report, err := module.BuildZReport(db.DB, date, date.AddDate(0, 0, 1))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to fetch report"})
+	}
+	return c.Status(fiber.StatusOK).JSON(report)
+}
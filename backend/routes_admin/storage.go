@@ -0,0 +1,45 @@
+package routes_admin
+
+import (
+	"time"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/middleware"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RunStorageGC godoc
+// @Summary Reconcile storage with the database
+// @Description Lists product-images and order-slips, reports objects no row references (orphans) and ready images whose files are missing (dangling). Orphans older than grace_hours are deleted unless dry_run=true. Defaults to a dry run.
+// @Tags storage
+// @Produce json
+// @Param dry_run query bool false "Only report (default true)"
+// @Param grace_hours query number false "Keep orphans younger than this (default 24)"
+// @Success 200 {object} models.StorageGCResponse
+// @Router /admin/storage/gc [post]
+func RunStorageGC(c *fiber.Ctx) error {
+	dryRun := c.QueryBool("dry_run", true)
+	graceHours := c.QueryFloat("grace_hours", module.DefaultStorageGCGrace.Hours())
+	if graceHours < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "grace_hours must not be negative"})
+	}
+	grace := time.Duration(graceHours * float64(time.Hour))
+
+	resp, err := module.CollectStorageGarbage(c.UserContext(), db.DB, db.Storage, grace, dryRun)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if resp.Deleted > 0 {
+		actorID, _ := c.Locals("userid").(string)
+		entry := module.NewAuditLog(actorID, "storage.gc", "storage", "", nil, resp)
+		middleware.AuditRequest(c, &entry)
+		if err := module.RecordAudit(db.DB, entry); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record audit log"})
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
@@ -0,0 +1,206 @@
+package routes_admin
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/middleware"
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// GetTiers godoc
+// @Summary List membership tiers
+// @Tags loyalty
+// @Produce json
+// @Success 200 {array} models.MembershipTier
+// @Router /admin/tiers [get]
+func GetTiers(c *fiber.Ctx) error {
+	var tiers []models.MembershipTier
+	if err := db.DB.Order("min_spend").Find(&tiers).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch tiers"})
+	}
+	return c.Status(fiber.StatusOK).JSON(tiers)
+}
+
+// CreateTier godoc
+// @Summary Create a membership tier
+// @Description Users reach the tier with the highest min_spend at most their 12 month spend. Takes effect at the next recalculation.
+// @Tags loyalty
+// @Accept json
+// @Produce json
+// @Param request body models.BodyTierRequest true "Tier"
+// @Success 201 {object} models.MembershipTier
+// @Router /admin/tiers [post]
+func CreateTier(c *fiber.Ctx) error {
+	var body models.BodyTierRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	if err := validateTier(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	tier := models.MembershipTier{}
+	applyTier(&tier, &body)
+	actorID, _ := c.Locals("userid").(string)
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&tier).Error; err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(actorID, "tier.create", "membership_tier", strconv.Itoa(int(tier.ID)), nil, tier)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A tier with that name already exists"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create tier"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(tier)
+}
+
+// UpdateTier godoc
+// @Summary Replace a membership tier
+// @Description Benefits apply right away; a changed min_spend moves users at the next recalculation
+// @Tags loyalty
+// @Accept json
+// @Produce json
+// @Param tier_id path int true "Tier ID"
+// @Param request body models.BodyTierRequest true "Tier"
+// @Success 200 {object} models.MembershipTier
+// @Router /admin/tiers/{tier_id} [put]
+func UpdateTier(c *fiber.Ctx) error {
+	tierID, err := c.ParamsInt("tier_id")
+	if err != nil || tierID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid tier ID"})
+	}
+	var body models.BodyTierRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	if err := validateTier(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var tier models.MembershipTier
+	actorID, _ := c.Locals("userid").(string)
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&tier, tierID).Error; err != nil {
+			return err
+		}
+		before := tier
+		applyTier(&tier, &body)
+		if err := tx.Save(&tier).Error; err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(actorID, "tier.update", "membership_tier", strconv.Itoa(int(tier.ID)), before, tier)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Tier not found"})
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A tier with that name already exists"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update tier"})
+	}
+	return c.Status(fiber.StatusOK).JSON(tier)
+}
+
+// DeleteTier godoc
+// @Summary Delete a membership tier
+// @Description Members lose the tier until the next recalculation places them in another one
+// @Tags loyalty
+// @Produce json
+// @Param tier_id path int true "Tier ID"
+// @Success 200 {object} models.MessageResponse
+// @Router /admin/tiers/{tier_id} [delete]
+func DeleteTier(c *fiber.Ctx) error {
+	tierID, err := c.ParamsInt("tier_id")
+	if err != nil || tierID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid tier ID"})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var tier models.MembershipTier
+		if err := tx.First(&tier, tierID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("tier_id = ?", tier.ID).UpdateColumn("tier_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&tier).Error; err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(actorID, "tier.delete", "membership_tier", strconv.Itoa(int(tier.ID)), tier, nil)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Tier not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete tier"})
+	}
+	return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
+		Message: "Tier deleted",
+	})
+}
+
+// RecalculateTiers godoc
+// @Summary Recalculate every user's tier now
+// @Description Runs the nightly recalculation immediately, e.g. after changing tier thresholds
+// @Tags loyalty
+// @Produce json
+// @Success 200 {object} models.TierRecalculateResponse
+// @Router /admin/tiers/recalculate [post]
+func RecalculateTiers(c *fiber.Ctx) error {
+	var changed int64
+	actorID, _ := c.Locals("userid").(string)
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if changed, err = module.RecalculateTiers(tx, nil, time.Now()); err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(actorID, "tier.recalculate", "membership_tier", "", nil, fiber.Map{"changed": changed})
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to recalculate tiers"})
+	}
+	return c.Status(fiber.StatusOK).JSON(models.TierRecalculateResponse{Changed: changed})
+}
+
+func validateTier(body *models.BodyTierRequest) error {
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" {
+		return errors.New("name is required")
+	}
+	if body.MinSpend <= 0 {
+		return errors.New("min_spend must be above 0")
+	}
+	if body.DiscountPercent < 0 || body.DiscountPercent > 100 {
+		return errors.New("discount_percent must be between 0 and 100")
+	}
+	return nil
+}
+
+func applyTier(tier *models.MembershipTier, body *models.BodyTierRequest) {
+	tier.Name = body.Name
+	tier.MinSpend = body.MinSpend
+	tier.DiscountPercent = body.DiscountPercent
+	tier.FreeDelivery = body.FreeDelivery
+	tier.EarlyAccess = body.EarlyAccess
+}
@@ -0,0 +1,498 @@
+package routes_admin
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"Bakery_Pos/db"
+	"Bakery_Pos/middleware"
+	"Bakery_Pos/models"
+	"Bakery_Pos/module"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetRoles godoc
+// @Summary List roles and their permissions
+// @Tags user
+// @Produce json
+// @Success 200 {array} models.RoleResponse
+// @Router /admin/roles [get]
+func GetRoles(c *fiber.Ctx) error {
+	roles := make([]models.RoleResponse, 0, len(models.RolePermissions))
+	for _, name := range models.Roles() {
+		roles = append(roles, models.RoleResponse{
+			Name:        name,
+			Permissions: models.RolePermissions[name],
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(roles)
+}
+
+// UpdateUserRole godoc
+// @Summary Assign a role to a user
+// @Description The change is audit logged and applies from the user's next login. The last Admin cannot be demoted.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param request body models.BodyUpdateRoleRequest true "New role"
+// @Success 200 {object} models.UserResponse
+// @Router /admin/users/{user_id}/role [put]
+func UpdateUserRole(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	var body models.BodyUpdateRoleRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	if !models.ValidRole(body.Role) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown role " + body.Role})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	var user models.User
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+		if user.Role == body.Role {
+			return nil
+		}
+		if err := ensureNotLastAdmin(tx, &user); err != nil {
+			return err
+		}
+
+		before := user.Role
+		if err := tx.Model(&user).Update("role", body.Role).Error; err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(actorID, "user.role_change", "user", user.ID.String(),
+			fiber.Map{"role": before}, fiber.Map{"role": body.Role})
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if errors.Is(err, errLastAdmin) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Cannot remove the last Admin"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update role"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(user.ToResponse())
+}
+
+var errLastAdmin = errors.New("cannot remove the last admin")
+
+// ensureNotLastAdmin fails with errLastAdmin when user is the only active Admin,
+// so the shop cannot be locked out of its own back office. The active Admin
+// rows stay locked until tx ends, so concurrent changes to two Admins cannot
+// both pass.
+func ensureNotLastAdmin(tx *gorm.DB, user *models.User) error {
+	if user.Role != models.RoleAdmin || user.DisabledAt != nil {
+		return nil
+	}
+	var admins []uuid.UUID
+	if err := tx.Model(&models.User{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("role = ? AND disabled_at IS NULL", models.RoleAdmin).
+		Order("id").Pluck("id", &admins).Error; err != nil {
+		return err
+	}
+	// user may have been demoted or disabled while we waited for the lock
+	if !slices.Contains(admins, user.ID) {
+		return nil
+	}
+	if len(admins) <= 1 {
+		return errLastAdmin
+	}
+	return nil
+}
+
+// userWithStats is a user row joined with its order totals.
+type userWithStats struct {
+	models.User
+	OrderCount    int64
+	LifetimeSpend float64
+	LastOrderAt   *time.Time
+}
+
+func (u *userWithStats) toResponse() models.AdminUserResponse {
+	return models.AdminUserResponse{
+		UserResponse:  u.User.ToResponse(),
+		CreatedAt:     u.CreatedAt,
+		DisabledAt:    u.DisabledAt,
+		OrderCount:    u.OrderCount,
+		LifetimeSpend: u.LifetimeSpend,
+		LastOrderAt:   u.LastOrderAt,
+	}
+}
+
+// withOrderStats joins order totals onto a users query. Lifetime spend only
+// counts orders past pending, unpaid and cancelled orders are not revenue.
+func withOrderStats(query *gorm.DB) *gorm.DB {
+	return query.
+		Select(`users.*, COUNT(orders.id) AS order_count,
+			COALESCE(SUM(CASE WHEN orders.status NOT IN ('pending', 'cancelled') THEN orders.total ELSE 0 END), 0) AS lifetime_spend,
+			MAX(orders.created_at) AS last_order_at`).
+		Joins("LEFT JOIN orders ON orders.user_id = users.id").
+		Group("users.id")
+}
+
+// GetUsers godoc
+// @Summary List users
+// @Description Search by username, name or phone with q, filter by role and status (active or disabled). Includes order count and lifetime spend.
+// @Tags user
+// @Produce json
+// @Param q query string false "Search text"
+// @Param role query string false "Role, e.g. Member"
+// @Param status query string false "active or disabled"
+// @Param limit query int false "Page size (default 50)"
+// @Param page query int false "Page number (default 1)"
+// @Success 200 {object} models.AdminUserListResponse
+// @Router /admin/users [get]
+func GetUsers(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	page := c.QueryInt("page", 1)
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	query := db.DB.Model(&models.User{})
+	if q := c.Query("q"); q != "" {
+		like := fmt.Sprintf("%%%s%%", q)
+		query = query.Where("users.username ILIKE ? OR users.name ILIKE ? OR users.phone_number LIKE ?", like, like, like)
+	}
+	if role := c.Query("role"); role != "" {
+		query = query.Where("users.role = ?", role)
+	}
+	switch c.Query("status") {
+	case "":
+	case "active":
+		query = query.Where("users.disabled_at IS NULL")
+	case "disabled":
+		query = query.Where("users.disabled_at IS NOT NULL")
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "status must be active or disabled"})
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to count users"})
+	}
+
+	var rows []userWithStats
+	if err := withOrderStats(query).Order("users.created_at DESC").Limit(limit).Offset((page - 1) * limit).Scan(&rows).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch users"})
+	}
+
+	resp := models.AdminUserListResponse{
+		Data:  make([]models.AdminUserResponse, 0, len(rows)),
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	}
+	for i := range rows {
+		resp.Data = append(resp.Data, rows[i].toResponse())
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetUserByID godoc
+// @Summary Get a user with order statistics
+// @Tags user
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Success 200 {object} models.AdminUserResponse
+// @Router /admin/users/{user_id} [get]
+func GetUserByID(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	var rows []userWithStats
+	if err := withOrderStats(db.DB.Model(&models.User{}).Where("users.id = ?", userID)).Scan(&rows).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch user"})
+	}
+	if len(rows) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(rows[0].toResponse())
+}
+
+// UpdateUserStatus godoc
+// @Summary Enable or disable a user
+// @Description Disabled users cannot log in and their sessions are revoked. Admins cannot disable themselves or the last Admin.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param request body models.BodyUserStatusRequest true "Disabled flag"
+// @Success 200 {object} models.UserResponse
+// @Router /admin/users/{user_id}/status [put]
+func UpdateUserStatus(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	var body models.BodyUserStatusRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	if body.Disabled && actorID == userID.String() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You cannot disable your own account"})
+	}
+
+	var user models.User
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+		if (user.DisabledAt != nil) == body.Disabled {
+			return nil
+		}
+
+		action := "user.enable"
+		var disabledAt *time.Time
+		if body.Disabled {
+			if err := ensureNotLastAdmin(tx, &user); err != nil {
+				return err
+			}
+			action = "user.disable"
+			now := time.Now()
+			disabledAt = &now
+		}
+
+		before := user.DisabledAt
+		if err := tx.Model(&user).Update("disabled_at", disabledAt).Error; err != nil {
+			return err
+		}
+		user.DisabledAt = disabledAt
+		if body.Disabled {
+			if err := module.RevokeAllSessions(tx, user.ID); err != nil {
+				return err
+			}
+		}
+		entry := module.NewAuditLog(actorID, action, "user", user.ID.String(),
+			fiber.Map{"disabled_at": before}, fiber.Map{"disabled_at": disabledAt})
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if errors.Is(err, errLastAdmin) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Cannot disable the last Admin"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update user status"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(user.ToResponse())
+}
+
+// ResetUserPassword godoc
+// @Summary Set a new password for a user
+// @Description Revokes all of the user's sessions. The password itself is never written to the audit log.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param request body models.BodyAdminResetPasswordRequest true "New password"
+// @Success 200 {object} models.MessageResponse
+// @Router /admin/users/{user_id}/reset-password [post]
+func ResetUserPassword(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	var body models.BodyAdminResetPasswordRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	if err := module.ValidatePassword(body.Password, ""); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	hashedPassword, err := module.HashPassword(body.Password)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to hash password"})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.User{}).Where("id = ?", userID).Update("password", hashedPassword)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := module.RevokeAllSessions(tx, userID); err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(actorID, "user.password_reset", "user", userID.String(), nil, nil)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset password"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
+		Message: "Password reset successfully",
+	})
+}
+
+// DeleteUser godoc
+// @Summary Soft delete a user
+// @Description The account can no longer log in; orders are kept. Admins cannot delete themselves or the last Admin.
+// @Tags user
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Success 200 {object} models.MessageResponse
+// @Router /admin/users/{user_id} [delete]
+func DeleteUser(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	if actorID == userID.String() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You cannot delete your own account"})
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+		if err := ensureNotLastAdmin(tx, &user); err != nil {
+			return err
+		}
+		if err := module.RevokeAllSessions(tx, user.ID); err != nil {
+			return err
+		}
+		if err := tx.Delete(&user).Error; err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(actorID, "user.delete", "user", user.ID.String(),
+			fiber.Map{"role": user.Role}, nil)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if errors.Is(err, errLastAdmin) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Cannot delete the last Admin"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete user"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
+		Message: "User deleted successfully",
+	})
+}
+
+// UnlockUser godoc
+// @Summary Clear a user's failed login counter and lockout
+// @Tags user
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Success 200 {object} models.MessageResponse
+// @Router /admin/users/{user_id}/lockout [delete]
+func UnlockUser(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	var user models.User
+	if err := db.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	key := module.UserAttemptKey(user.Username)
+	if err := db.Attempts.Reset(c.UserContext(), key); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to unlock user"})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	entry := module.NewAuditLog(actorID, "security.unlock", "login", key, nil, fiber.Map{"user_id": user.ID})
+	middleware.AuditRequest(c, &entry)
+	if err := module.RecordAudit(db.DB, entry); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record audit log"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
+		Message: "User unlocked",
+	})
+}
+
+// ResetUserMFA godoc
+// @Summary Turn off a user's two-factor authentication
+// @Description For a lost authenticator. The user's sessions are revoked and they can enroll again after logging in with the password.
+// @Tags user
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Success 200 {object} models.MessageResponse
+// @Router /admin/users/{user_id}/mfa [delete]
+func ResetUserMFA(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	var user models.User
+	if err := db.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if user.MFAEnabledAt == nil && user.MFASecret == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Two-factor authentication is not enabled"})
+	}
+
+	actorID, _ := c.Locals("userid").(string)
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := module.ClearMFA(tx, user.ID); err != nil {
+			return err
+		}
+		if err := module.RevokeAllSessions(tx, user.ID); err != nil {
+			return err
+		}
+		entry := module.NewAuditLog(actorID, "user.mfa_reset", "user", user.ID.String(), nil, nil)
+		middleware.AuditRequest(c, &entry)
+		return module.RecordAudit(tx, entry)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset two-factor authentication"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.MessageResponse{
+		Message: "Two-factor authentication reset",
+	})
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Errors shared by all backends. Backend specific errors wrap one of these so
+// callers can use errors.Is regardless of the driver in use.
+var (
+	ErrNotFound     = errors.New("storage: object not found")
+	ErrConflict     = errors.New("storage: object already exists")
+	ErrUnauthorized = errors.New("storage: unauthorized")
+)
+
+// HTTPStatus maps a storage error to the status a handler should answer with.
+func HTTPStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrUnauthorized):
+		// the server's credentials were rejected, which is not the caller's fault
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ErrorForStatus returns the shared error matching an HTTP status, or nil.
+func ErrorForStatus(status int) error {
+	switch status {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LocalMountPath is where Register expects the local store routes to be mounted.
+const LocalMountPath = "/storage"
+
+// LocalStore keeps objects on the local disk and serves them from the Fiber app.
+// Upload URLs are signed with HMAC-SHA256 so only URLs issued by the server work.
+type LocalStore struct {
+	Root      string        // directory holding one sub directory per bucket
+	BaseURL   string        // public URL of LocalMountPath, e.g. http://localhost:5000/storage
+	UploadTTL time.Duration // lifetime of signed upload URLs
+
+	secret []byte
+	mu     sync.RWMutex
+	public map[string]bool
+}
+
+func NewLocalStore(root, baseURL string, secret []byte) (*LocalStore, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("local storage signing key is empty")
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	return &LocalStore{
+		Root:      root,
+		BaseURL:   strings.TrimRight(baseURL, "/"),
+		UploadTTL: 2 * time.Hour,
+		secret:    secret,
+		public:    make(map[string]bool),
+	}, nil
+}
+
+func (s *LocalStore) CreateBucket(_ context.Context, name string, public bool) error {
+	dir, err := s.resolve(name, "")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.public[name] = public
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *LocalStore) GenerateUploadURL(_ context.Context, bucket, objectPath string) (signedURL, publicURL string, err error) {
+	if _, err := s.resolve(bucket, objectPath); err != nil {
+		return "", "", err
+	}
+	expires := time.Now().Add(s.UploadTTL).Unix()
+	sig := s.sign("PUT", bucket, objectPath, expires)
+	signedURL = fmt.Sprintf("%s/upload/%s/%s?expires=%d&signature=%s", s.BaseURL, bucket, escapePath(objectPath), expires, sig)
+	return signedURL, s.GetPublicURL(bucket, objectPath), nil
+}
+
+func (s *LocalStore) GetPublicURL(bucket, objectPath string) string {
+	return fmt.Sprintf("%s/public/%s/%s", s.BaseURL, bucket, escapePath(objectPath))
+}
+
+func (s *LocalStore) GenerateDownloadURL(_ context.Context, bucket, objectPath string, expiresIn time.Duration) (string, error) {
+	if _, err := s.resolve(bucket, objectPath); err != nil {
+		return "", err
+	}
+	expires := time.Now().Add(expiresIn).Unix()
+	sig := s.sign("GET", bucket, objectPath, expires)
+	return fmt.Sprintf("%s/signed/%s/%s?expires=%d&signature=%s", s.BaseURL, bucket, escapePath(objectPath), expires, sig), nil
+}
+
+func (s *LocalStore) Upload(_ context.Context, bucket, objectPath string, r io.Reader, size int64, contentType string) error {
+	file, err := s.resolve(bucket, objectPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *LocalStore) Download(_ context.Context, bucket, objectPath string) ([]byte, error) {
+	file, err := s.resolve(bucket, objectPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s/%s", ErrNotFound, bucket, objectPath)
+	}
+	return data, err
+}
+
+func (s *LocalStore) Stat(_ context.Context, bucket, objectPath string) (ObjectInfo, error) {
+	file, err := s.resolve(bucket, objectPath)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	info, err := os.Stat(file)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return ObjectInfo{}, fmt.Errorf("%w: %s/%s", ErrNotFound, bucket, objectPath)
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{
+		Path:        objectPath,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(path.Ext(objectPath)),
+		UpdatedAt:   info.ModTime(),
+	}, nil
+}
+
+func (s *LocalStore) RemoveFile(_ context.Context, bucket, objectPath string) error {
+	file, err := s.resolve(bucket, objectPath)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStore) List(_ context.Context, bucket, prefix string) ([]ObjectInfo, error) {
+	bucketDir, err := s.resolve(bucket, "")
+	if err != nil {
+		return nil, err
+	}
+	root, err := s.resolve(bucket, strings.Trim(prefix, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []ObjectInfo
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(bucketDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{
+			Path:        rel,
+			Size:        info.Size(),
+			ContentType: mime.TypeByExtension(path.Ext(rel)),
+			UpdatedAt:   info.ModTime(),
+		})
+		return nil
+	})
+	return objects, err
+}
+
+// Register mounts the download and signed upload handlers. router must be
+// mounted at LocalMountPath so the URLs built from BaseURL resolve.
+func (s *LocalStore) Register(router fiber.Router) {
+	router.Get("/public/:bucket/*", s.handleDownload)
+	router.Get("/signed/:bucket/*", s.handleSignedDownload)
+	router.Put("/upload/:bucket/*", s.handleUpload)
+}
+
+func (s *LocalStore) handleDownload(c *fiber.Ctx) error {
+	bucket := c.Params("bucket")
+	s.mu.RLock()
+	public := s.public[bucket]
+	s.mu.RUnlock()
+	if !public {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Object not found"})
+	}
+
+	objectPath, err := url.PathUnescape(c.Params("*"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid object path"})
+	}
+	return s.sendObject(c, bucket, objectPath)
+}
+
+func (s *LocalStore) handleSignedDownload(c *fiber.Ctx) error {
+	bucket := c.Params("bucket")
+	objectPath, err := url.PathUnescape(c.Params("*"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid object path"})
+	}
+	if err := s.verify(c, "GET", bucket, objectPath); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
+	return s.sendObject(c, bucket, objectPath)
+}
+
+func (s *LocalStore) sendObject(c *fiber.Ctx, bucket, objectPath string) error {
+	file, err := s.resolve(bucket, objectPath)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if _, err := os.Stat(file); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Object not found"})
+	}
+	return c.SendFile(file)
+}
+
+func (s *LocalStore) handleUpload(c *fiber.Ctx) error {
+	bucket := c.Params("bucket")
+	objectPath, err := url.PathUnescape(c.Params("*"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid object path"})
+	}
+
+	if err := s.verify(c, "PUT", bucket, objectPath); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := s.Upload(c.UserContext(), bucket, objectPath, bytes.NewReader(c.Body()), int64(len(c.Body())), c.Get(fiber.HeaderContentType)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to store object"})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"Key": bucket + "/" + objectPath})
+}
+
+// verify checks the expires and signature query parameters of a signed URL.
+func (s *LocalStore) verify(c *fiber.Ctx, method, bucket, objectPath string) error {
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return errors.New("URL expired")
+	}
+	expected := s.sign(method, bucket, objectPath, expires)
+	if !hmac.Equal([]byte(expected), []byte(c.Query("signature"))) {
+		return errors.New("Invalid signature")
+	}
+	return nil
+}
+
+func (s *LocalStore) sign(method, bucket, objectPath string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s\n%s/%s\n%d", method, bucket, objectPath, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// resolve maps a bucket and object path to a file below Root, rejecting
+// anything that would escape it.
+func (s *LocalStore) resolve(bucket, objectPath string) (string, error) {
+	if bucket == "" || strings.ContainsAny(bucket, `/\`) || bucket == "." || bucket == ".." {
+		return "", fmt.Errorf("invalid bucket name %q", bucket)
+	}
+	clean := path.Clean("/" + objectPath)
+	if objectPath != "" && (clean == "/" || strings.Contains(objectPath, "..")) {
+		return "", fmt.Errorf("invalid object path %q", objectPath)
+	}
+	return filepath.Join(s.Root, bucket, filepath.FromSlash(clean)), nil
+}
+
+func escapePath(objectPath string) string {
+	parts := strings.Split(objectPath, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config points the S3 store at AWS or any S3 compatible server such as MinIO.
+type S3Config struct {
+	Endpoint  string // host[:port], without scheme
+	AccessKey string
+	SecretKey string
+	Region    string
+	UseSSL    bool
+	// PublicURL is the base objects are served from, defaults to the endpoint.
+	PublicURL string
+}
+
+// S3Store stores objects in S3 compatible buckets using path style URLs.
+// Upload URLs are SigV4 presigned PUTs.
+type S3Store struct {
+	client    *minio.Client
+	region    string
+	publicURL string
+	UploadTTL time.Duration
+}
+
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("S3 endpoint is not set")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure:       cfg.UseSSL,
+		Region:       cfg.Region,
+		BucketLookup: minio.BucketLookupPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publicURL := cfg.PublicURL
+	if publicURL == "" {
+		scheme := "http"
+		if cfg.UseSSL {
+			scheme = "https"
+		}
+		publicURL = fmt.Sprintf("%s://%s", scheme, cfg.Endpoint)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := client.ListBuckets(ctx); err != nil {
+		return nil, fmt.Errorf("failed to connect to S3: %w", err)
+	}
+
+	return &S3Store{
+		client:    client,
+		region:    cfg.Region,
+		publicURL: strings.TrimRight(publicURL, "/"),
+		UploadTTL: 2 * time.Hour,
+	}, nil
+}
+
+// CreateBucket creates the bucket when missing and applies the access policy:
+// public buckets allow anonymous GetObject, private buckets have no policy.
+func (s *S3Store) CreateBucket(ctx context.Context, name string, public bool) error {
+	exists, err := s.client.BucketExists(ctx, name)
+	if err != nil {
+		return s3Error(err)
+	}
+	if !exists {
+		if err := s.client.MakeBucket(ctx, name, minio.MakeBucketOptions{Region: s.region}); err != nil {
+			return s3Error(err)
+		}
+	}
+
+	policy := ""
+	if public {
+		policy = fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":["*"]},"Action":["s3:GetObject"],"Resource":["arn:aws:s3:::%s/*"]}]}`, name)
+	}
+	return s3Error(s.client.SetBucketPolicy(ctx, name, policy))
+}
+
+func (s *S3Store) GenerateUploadURL(ctx context.Context, bucket, objectPath string) (signedURL, publicURL string, err error) {
+	u, err := s.client.PresignedPutObject(ctx, bucket, objectPath, s.UploadTTL)
+	if err != nil {
+		return "", "", s3Error(err)
+	}
+	return u.String(), s.GetPublicURL(bucket, objectPath), nil
+}
+
+func (s *S3Store) GenerateDownloadURL(ctx context.Context, bucket, objectPath string, expiresIn time.Duration) (string, error) {
+	u, err := s.client.PresignedGetObject(ctx, bucket, objectPath, expiresIn, nil)
+	if err != nil {
+		return "", s3Error(err)
+	}
+	return u.String(), nil
+}
+
+func (s *S3Store) GetPublicURL(bucket, objectPath string) string {
+	return fmt.Sprintf("%s/%s/%s", s.publicURL, bucket, escapePath(objectPath))
+}
+
+func (s *S3Store) Upload(ctx context.Context, bucket, objectPath string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, bucket, objectPath, r, size,
+		minio.PutObjectOptions{ContentType: contentType})
+	return s3Error(err)
+}
+
+func (s *S3Store) Download(ctx context.Context, bucket, objectPath string) ([]byte, error) {
+	obj, err := s.client.GetObject(ctx, bucket, objectPath, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	defer obj.Close()
+
+	// GetObject is lazy, a missing key only surfaces on the first read
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	return data, nil
+}
+
+func (s *S3Store) Stat(ctx context.Context, bucket, objectPath string) (ObjectInfo, error) {
+	obj, err := s.client.StatObject(ctx, bucket, objectPath, minio.StatObjectOptions{})
+	if err != nil {
+		return ObjectInfo{}, s3Error(err)
+	}
+	return ObjectInfo{
+		Path:        obj.Key,
+		Size:        obj.Size,
+		ContentType: obj.ContentType,
+		UpdatedAt:   obj.LastModified,
+	}, nil
+}
+
+func (s *S3Store) RemoveFile(ctx context.Context, bucket, objectPath string) error {
+	return s3Error(s.client.RemoveObject(ctx, bucket, objectPath, minio.RemoveObjectOptions{}))
+}
+
+func (s *S3Store) List(ctx context.Context, bucket, prefix string) ([]ObjectInfo, error) {
+	folder := strings.Trim(prefix, "/")
+	if folder != "" {
+		folder += "/"
+	}
+
+	var objects []ObjectInfo
+	for obj := range s.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{
+		Prefix:    folder,
+		Recursive: true,
+	}) {
+		if obj.Err != nil {
+			return nil, s3Error(obj.Err)
+		}
+		objects = append(objects, ObjectInfo{
+			Path:        obj.Key,
+			Size:        obj.Size,
+			ContentType: obj.ContentType,
+			UpdatedAt:   obj.LastModified,
+		})
+	}
+	return objects, nil
+}
+
+// s3Error wraps S3 error codes in the shared storage errors.
+func s3Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NoSuchBucket":
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	case "BucketAlreadyExists", "BucketAlreadyOwnedByYou":
+		return fmt.Errorf("%w: %v", ErrConflict, err)
+	case "AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch":
+		return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+	return err
+}
@@ -0,0 +1,127 @@
+//go:build integration
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Run against a local MinIO with
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	S3_ENDPOINT=localhost:9000 S3_ACCESS_KEY=minioadmin S3_SECRET_KEY=minioadmin \
+//		go test -tags integration ./storage
+func newTestS3Store(t *testing.T) (*S3Store, string) {
+	t.Helper()
+	endpoint := os.Getenv("S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3_ENDPOINT is not set")
+	}
+	useSSL, _ := strconv.ParseBool(os.Getenv("S3_USE_SSL"))
+	store, err := NewS3Store(S3Config{
+		Endpoint:  endpoint,
+		AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("S3_SECRET_KEY"),
+		Region:    os.Getenv("S3_REGION"),
+		UseSSL:    useSSL,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+
+	bucket := "bakery-pos-test-" + uuid.New().String()[:8]
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := store.CreateBucket(ctx, bucket, false); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		objects, _ := store.List(ctx, bucket, "")
+		for _, obj := range objects {
+			store.RemoveFile(ctx, bucket, obj.Path)
+		}
+		store.client.RemoveBucket(ctx, bucket)
+	})
+	return store, bucket
+}
+
+func TestS3StoreRoundTrip(t *testing.T) {
+	store, bucket := newTestS3Store(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	objectPath := "slips/ORD-test.jpg"
+	data := []byte("not really a jpeg")
+	if err := store.Upload(ctx, bucket, objectPath, bytes.NewReader(data), int64(len(data)), "image/jpeg"); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+
+	got, err := store.Download(ctx, bucket, objectPath)
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("Download = %q, want %q", got, data)
+	}
+
+	// the bucket is private, so only the signed URL can read the object
+	signed, err := store.GenerateDownloadURL(ctx, bucket, objectPath, time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateDownloadURL: %v", err)
+	}
+	resp, err := http.Get(signed)
+	if err != nil {
+		t.Fatalf("GET signed URL: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+		t.Fatalf("GET signed URL = %d %q, want 200 %q", resp.StatusCode, body, data)
+	}
+	if resp, err := http.Get(store.GetPublicURL(bucket, objectPath)); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Fatalf("unsigned GET of a private object succeeded")
+		}
+	}
+
+	objects, err := store.List(ctx, bucket, "slips")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Path != objectPath || objects[0].Size != int64(len(data)) {
+		t.Fatalf("List = %+v, want one %s of %d bytes", objects, objectPath, len(data))
+	}
+
+	if err := store.RemoveFile(ctx, bucket, objectPath); err != nil {
+		t.Fatalf("RemoveFile: %v", err)
+	}
+	if _, err := store.Download(ctx, bucket, objectPath); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Download after RemoveFile: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3StoreMissingKey(t *testing.T) {
+	store, bucket := newTestS3Store(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := store.Download(ctx, bucket, "does/not/exist"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Download: got %v, want ErrNotFound", err)
+	}
+	if _, err := store.Download(ctx, "no-such-bucket-"+uuid.New().String()[:8], "x"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Download from missing bucket: got %v, want ErrNotFound", err)
+	}
+}
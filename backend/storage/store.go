@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// ObjectStore is the blob storage behind product images and payment slips.
+// Object paths are relative to the bucket and use forward slashes. Errors wrap
+// ErrNotFound, ErrConflict or ErrUnauthorized where one applies.
+type ObjectStore interface {
+	// CreateBucket creates the bucket if needed; public buckets serve objects
+	// without a signature.
+	CreateBucket(ctx context.Context, name string, public bool) error
+	// GenerateUploadURL returns a short-lived URL the client can PUT the file to,
+	// and the URL the object will be served from afterwards.
+	GenerateUploadURL(ctx context.Context, bucket, objectPath string) (signedURL, publicURL string, err error)
+	GetPublicURL(bucket, objectPath string) string
+	// GenerateDownloadURL returns a URL that reads the object for expiresIn,
+	// also for private buckets.
+	GenerateDownloadURL(ctx context.Context, bucket, objectPath string, expiresIn time.Duration) (string, error)
+	// Upload streams size bytes of r into the object, replacing it if it
+	// exists. size may be -1 when unknown.
+	Upload(ctx context.Context, bucket, objectPath string, r io.Reader, size int64, contentType string) error
+	Download(ctx context.Context, bucket, objectPath string) ([]byte, error)
+	// Stat returns the object's metadata, or ErrNotFound when it does not
+	// exist. Signing a URL does not check that, so check first with Stat.
+	Stat(ctx context.Context, bucket, objectPath string) (ObjectInfo, error)
+	RemoveFile(ctx context.Context, bucket, objectPath string) error
+	// List returns every object in the folder prefix ("" for the whole
+	// bucket), recursing into sub folders.
+	List(ctx context.Context, bucket, prefix string) ([]ObjectInfo, error)
+}
+
+type ObjectInfo struct {
+	Path        string    `json:"path"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -1,27 +1,20 @@
 package storageapi
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-)
 
-func (c *Client) ListBuckets() ([]BucketInfo, error) {
-	resp, err := c.DoRequest("GET", "/bucket", struct{}{})
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	"Bakery_Pos/storage"
+)
 
-	data, err := io.ReadAll(resp.Body)
+func (c *Client) ListBuckets(ctx context.Context) ([]BucketInfo, error) {
+	data, err := c.DoRequest(ctx, "GET", "/bucket", nil)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("failed to list buckets: %s", string(data))
-	}
-
 	var buckets []BucketInfo
 	if err := json.Unmarshal(data, &buckets); err != nil {
 		return nil, err
@@ -30,7 +23,8 @@ func (c *Client) ListBuckets() ([]BucketInfo, error) {
 	return buckets, nil
 }
 
-func (c *Client) CreateBucket(name string, public bool) error {
+// CreateBucket creates the bucket, or updates its public flag when it already exists.
+func (c *Client) CreateBucket(ctx context.Context, name string, public bool) error {
 	urlPath := "/bucket"
 
 	payload := struct {
@@ -41,15 +35,30 @@ func (c *Client) CreateBucket(name string, public bool) error {
 		Public: public,
 	}
 
-	resp, err := c.DoRequest("POST", urlPath, payload)
+	_, err := c.DoRequest(ctx, "POST", urlPath, payload)
+	if errors.Is(err, storage.ErrConflict) {
+		return c.UpdateBucket(ctx, name, public)
+	}
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to create bucket: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) UpdateBucket(ctx context.Context, name string, public bool) error {
+	urlPath := fmt.Sprintf("/bucket/%s", name)
+
+	payload := struct {
+		ID     string `json:"id"`
+		Public bool   `json:"public"`
+	}{
+		ID:     name,
+		Public: public,
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to create bucket: %s", string(body))
+	if _, err := c.DoRequest(ctx, "PUT", urlPath, payload); err != nil {
+		return fmt.Errorf("failed to update bucket: %w", err)
 	}
 
 	return nil
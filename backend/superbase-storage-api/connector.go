@@ -2,14 +2,44 @@ package storageapi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"net/http"
+	"time"
 )
 
-func NewClient(projectID, anonKey, bearer string) *Client {
+type Option func(*Client)
+
+// WithHTTPClient replaces the default HTTP client (30s timeout).
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.HTTPClient = hc
+	}
+}
+
+// WithTimeout sets the per request timeout of the default HTTP client.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.HTTPClient = &http.Client{Timeout: d}
+	}
+}
+
+// WithRetry sets how often idempotent requests are retried on network errors,
+// 429 and 5xx responses, and the initial backoff between attempts.
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.MaxRetries = maxRetries
+		c.RetryBackoff = backoff
+	}
+}
+
+// NewClient builds a client and checks the connection by listing buckets.
+func NewClient(ctx context.Context, projectID, anonKey, bearer string, opts ...Option) (*Client, error) {
 	c := &Client{
 		ProjectID: projectID,
 		AnonKey:   anonKey,
@@ -17,52 +47,150 @@ func NewClient(projectID, anonKey, bearer string) *Client {
 		Headers: map[string]string{
 			"Authorization": "Bearer " + bearer,
 			"apikey":        bearer,
-			"Content-Type":  "application/json",
 		},
+		HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+		MaxRetries:   3,
+		RetryBackoff: 200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
 
-	buckets, err := c.ListBuckets()
-	if err == nil {
-		log.Println("✅ Connected to Supabase Storage")
-		for _, b := range buckets {
-			fmt.Printf("Bucket: %s, Public: %v\n", b.Name, b.Public)
-		}
-	} else {
-		log.Fatal("❌ Failed to connect to Supabase Storage:", err)
-		return nil
+	buckets, err := c.ListBuckets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Supabase Storage: %w", err)
+	}
+
+	log.Println("✅ Connected to Supabase Storage")
+	for _, b := range buckets {
+		fmt.Printf("Bucket: %s, Public: %v\n", b.Name, b.Public)
 	}
 
-	return c
+	return c, nil
 }
 
 func (s *Client) baseURL() string {
 	return fmt.Sprintf("https://%s.supabase.co/storage/v1", s.ProjectID)
 }
 
-func (c *Client) DoRequest(method, path string, payload any) (*http.Response, error) {
-	var body io.Reader
+type request struct {
+	method string
+	path   string
+	body   []byte
+	// stream replaces body for uploads; size is its length, or -1 if unknown
+	stream      io.Reader
+	size        int64
+	contentType string
+	headers     map[string]string
+	// idempotent requests are safe to send again after a failure
+	idempotent bool
+}
 
+// DoRequest sends payload as JSON and returns the response body. GET, PUT and
+// DELETE requests are retried; use doRaw to retry other methods.
+func (c *Client) DoRequest(ctx context.Context, method, path string, payload any) ([]byte, error) {
+	r := request{
+		method:     method,
+		path:       path,
+		idempotent: method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete,
+	}
 	if payload != nil {
 		jsonBytes, err := json.Marshal(payload)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal payload to JSON: %w", err)
 		}
-		body = bytes.NewReader(jsonBytes)
+		r.body = jsonBytes
+		r.contentType = "application/json"
 	}
+	return c.doRaw(ctx, r)
+}
 
-	req, err := http.NewRequest(method, c.baseURL()+path, body)
+// doRaw sends r, retrying idempotent requests with exponential backoff, and
+// returns the body of a 2xx response or an *Error.
+func (c *Client) doRaw(ctx context.Context, r request) ([]byte, error) {
+	// a stream can only be sent again if it can be rewound
+	seeker, canRewind := r.stream.(io.Seeker)
+	if r.stream != nil && !canRewind {
+		r.idempotent = false
+	}
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && canRewind {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+		data, err := c.send(ctx, r)
+		if err == nil {
+			return data, nil
+		}
+		if !r.idempotent || attempt >= c.MaxRetries || !retryable(ctx, err) {
+			return nil, err
+		}
+
+		wait := c.RetryBackoff << attempt
+		if wait > 0 {
+			wait += time.Duration(rand.Int63n(int64(wait) / 2))
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, r request) ([]byte, error) {
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	} else if r.stream != nil {
+		// hide any Seek and Close so the transport neither sniffs nor closes it
+		body = io.NopCloser(r.stream)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.method, c.baseURL()+r.path, body)
 	if err != nil {
 		return nil, err
 	}
+	if r.stream != nil && r.size >= 0 {
+		req.ContentLength = r.size
+	}
 
 	for k, v := range c.Headers {
 		req.Header.Set(k, v)
 	}
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	for k, v := range r.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-	resp, err := http.DefaultClient.Do(req)
+	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
-	return resp, nil
+	if resp.StatusCode >= 300 {
+		return nil, newError(resp.StatusCode, data)
+	}
+	return data, nil
+}
+
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return isRetryableStatus(apiErr.StatusCode)
+	}
+	// transport errors such as timeouts and resets
+	return true
 }
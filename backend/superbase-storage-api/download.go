@@ -1,31 +1,81 @@
 package storageapi
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"io"
+	"time"
+
+	"Bakery_Pos/storage"
 )
 
 func (c *Client) GetPublicURL(bucket, objectPath string) string {
 	return fmt.Sprintf("%s/object/public/%s/%s", c.baseURL(), bucket, objectPath)
 }
 
-func (c *Client) Download(bucket, path string) ([]byte, error) {
+func (c *Client) Download(ctx context.Context, bucket, path string) ([]byte, error) {
 	urlPath := fmt.Sprintf("/object/%s/%s", bucket, path)
 
-	resp, err := c.DoRequest("GET", urlPath, struct{}{})
+	data, err := c.DoRequest(ctx, "GET", urlPath, nil)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("download failed: %w", err)
 	}
-	defer resp.Body.Close()
 
-	data, err := io.ReadAll(resp.Body)
+	return data, nil
+}
+
+// Stat reads the object's metadata from the info endpoint.
+func (c *Client) Stat(ctx context.Context, bucket, objectPath string) (storage.ObjectInfo, error) {
+	data, err := c.DoRequest(ctx, "GET", fmt.Sprintf("/object/info/%s/%s", bucket, objectPath), nil)
 	if err != nil {
-		return nil, err
+		return storage.ObjectInfo{}, fmt.Errorf("stat failed: %w", err)
 	}
 
-	if resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("download failed: %s", string(data))
+	var res ObjectInfoResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		return storage.ObjectInfo{}, fmt.Errorf("failed to decode JSON: %w", err)
 	}
+	updated, _ := time.Parse(time.RFC3339, res.LastModified)
+	return storage.ObjectInfo{
+		Path:        objectPath,
+		Size:        res.Size,
+		ContentType: res.ContentType,
+		UpdatedAt:   updated,
+	}, nil
+}
 
-	return data, nil
+// CreateSignedURL signs a time limited download URL, which also works for
+// objects in private buckets.
+func (c *Client) CreateSignedURL(ctx context.Context, bucket, objectPath string, expiresIn int) (string, error) {
+	payload, err := json.Marshal(struct {
+		ExpiresIn int `json:"expiresIn"`
+	}{
+		ExpiresIn: expiresIn,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// signing has no side effects, so it is safe to retry
+	data, err := c.doRaw(ctx, request{
+		method:      "POST",
+		path:        fmt.Sprintf("/object/sign/%s/%s", bucket, objectPath),
+		body:        payload,
+		contentType: "application/json",
+		idempotent:  true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("sign failed: %w", err)
+	}
+
+	var res SignedURLResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		return "", fmt.Errorf("failed to decode JSON: %w", err)
+	}
+
+	return c.baseURL() + res.SignedURL, nil
+}
+
+func (c *Client) GenerateDownloadURL(ctx context.Context, bucket, objectPath string, expiresIn time.Duration) (string, error) {
+	return c.CreateSignedURL(ctx, bucket, objectPath, int(expiresIn.Seconds()))
 }
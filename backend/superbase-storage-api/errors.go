@@ -0,0 +1,55 @@
+package storageapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"Bakery_Pos/storage"
+)
+
+// Error is a failed Supabase Storage response. It unwraps to storage.ErrNotFound,
+// storage.ErrConflict or storage.ErrUnauthorized where one applies.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("supabase storage: %d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("supabase storage: %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return storage.ErrorForStatus(e.StatusCode)
+}
+
+func newError(status int, data []byte) *Error {
+	e := &Error{StatusCode: status, Message: string(data)}
+
+	var body errorBody
+	if err := json.Unmarshal(data, &body); err == nil && (body.Message != "" || body.Error != "") {
+		e.Code = body.Error
+		e.Message = body.Message
+		if code, err := strconv.Atoi(body.StatusCode); err == nil && code >= 400 {
+			e.StatusCode = code
+		}
+	}
+	if e.Message == "" {
+		e.Message = http.StatusText(status)
+	}
+	return e
+}
+
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
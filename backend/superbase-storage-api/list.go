@@ -0,0 +1,87 @@
+package storageapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"Bakery_Pos/storage"
+)
+
+const listPageSize = 1000
+
+// List returns every object in the folder prefix. Supabase lists one folder
+// level at a time, so sub folders are walked recursively.
+func (c *Client) List(ctx context.Context, bucket, prefix string) ([]storage.ObjectInfo, error) {
+	var objects []storage.ObjectInfo
+	if err := c.listFolder(ctx, bucket, strings.Trim(prefix, "/"), &objects); err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+func (c *Client) listFolder(ctx context.Context, bucket, folder string, out *[]storage.ObjectInfo) error {
+	for offset := 0; ; offset += listPageSize {
+		entries, err := c.listPage(ctx, bucket, folder, offset)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range entries {
+			full := e.Name
+			if folder != "" {
+				full = folder + "/" + e.Name
+			}
+			if e.ID == nil {
+				if err := c.listFolder(ctx, bucket, full, out); err != nil {
+					return err
+				}
+				continue
+			}
+			updated, _ := time.Parse(time.RFC3339, e.UpdatedAt)
+			*out = append(*out, storage.ObjectInfo{
+				Path:        full,
+				Size:        e.Metadata.Size,
+				ContentType: e.Metadata.Mimetype,
+				UpdatedAt:   updated,
+			})
+		}
+
+		if len(entries) < listPageSize {
+			return nil
+		}
+	}
+}
+
+func (c *Client) listPage(ctx context.Context, bucket, folder string, offset int) ([]FileObject, error) {
+	payload := ListRequest{
+		Prefix: folder,
+		Limit:  listPageSize,
+		Offset: offset,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// listing is a read, so it is retried like a GET
+	data, err := c.doRaw(ctx, request{
+		method:      "POST",
+		path:        fmt.Sprintf("/object/list/%s", bucket),
+		body:        body,
+		contentType: "application/json",
+		idempotent:  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list failed: %w", err)
+	}
+
+	var entries []FileObject
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -1,22 +1,15 @@
 package storageapi
 
 import (
+	"context"
 	"fmt"
-	"io"
 )
 
-func (c *Client) RemoveFile(bucket, path string) error {
+func (c *Client) RemoveFile(ctx context.Context, bucket, path string) error {
 	urlPath := fmt.Sprintf("/object/%s/%s", bucket, path)
 
-	resp, err := c.DoRequest("DELETE", urlPath, struct{}{})
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	data, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode >= 300 {
-		return fmt.Errorf("delete failed: %s", string(data))
+	if _, err := c.DoRequest(ctx, "DELETE", urlPath, nil); err != nil {
+		return fmt.Errorf("delete failed: %w", err)
 	}
 
 	return nil
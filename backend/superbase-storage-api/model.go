@@ -1,10 +1,23 @@
 package storageapi
 
+import (
+	"net/http"
+	"time"
+)
+
 type Client struct {
 	ProjectID string
 	AnonKey   string
 	Bearer    string
 	Headers   map[string]string
+
+	HTTPClient   *http.Client
+	MaxRetries   int           // extra attempts for idempotent requests
+	RetryBackoff time.Duration // first retry delay, doubled on each attempt
+}
+
+type SignedURLResponse struct {
+	SignedURL string `json:"signedURL"`
 }
 
 type SignUploadFile struct {
@@ -20,3 +33,35 @@ type BucketInfo struct {
 	Public    bool   `json:"public"`
 	Owner     string `json:"owner"`
 }
+
+type ListRequest struct {
+	Prefix string `json:"prefix"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+type FileObject struct {
+	Name      string  `json:"name"`
+	ID        *string `json:"id"` // nil for folders
+	UpdatedAt string  `json:"updated_at"`
+	CreatedAt string  `json:"created_at"`
+	Metadata  struct {
+		Size     int64  `json:"size"`
+		Mimetype string `json:"mimetype"`
+	} `json:"metadata"`
+}
+
+type ObjectInfoResponse struct {
+	Name         string `json:"name"`
+	Size         int64  `json:"size"`
+	ContentType  string `json:"content_type"`
+	LastModified string `json:"last_modified"`
+}
+
+// errorBody is the JSON Supabase Storage answers with on failure. statusCode is
+// a string and may differ from the HTTP status (not found is often a 400).
+type errorBody struct {
+	StatusCode string `json:"statusCode"`
+	Error      string `json:"error"`
+	Message    string `json:"message"`
+}
@@ -1,47 +1,50 @@
 package storageapi
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 )
 
-func (c *Client) Upload(bucket, path string, file []byte, contentType string) error {
-	urlPath := fmt.Sprintf("/object/%s/%s", bucket, path)
-
-	reqBody := bytes.NewReader(file)
-	resp, err := c.DoRequest("POST", urlPath, reqBody)
-	if err != nil {
-		return err
+// Upload streams size bytes of r as the raw request body. Uploads overwrite
+// existing objects, which also makes them safe to retry when r is an
+// io.Seeker; other readers are sent once.
+func (c *Client) Upload(ctx context.Context, bucket, path string, r io.Reader, size int64, contentType string) error {
+	if contentType == "" {
+		contentType = "application/octet-stream"
 	}
-	defer resp.Body.Close()
 
-	data, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode >= 300 {
-		return fmt.Errorf("upload failed: %s", string(data))
+	_, err := c.doRaw(ctx, request{
+		method:      "POST",
+		path:        fmt.Sprintf("/object/%s/%s", bucket, path),
+		stream:      r,
+		size:        size,
+		contentType: contentType,
+		headers:     map[string]string{"x-upsert": "true"},
+		idempotent:  true,
+	})
+	if err != nil {
+		return fmt.Errorf("upload failed: %w", err)
 	}
 
 	return nil
 }
 
-func (c *Client) GenerateUploadURL(bucket, objectPath string) (signedURL, publicURL string, err error) {
+func (c *Client) GenerateUploadURL(ctx context.Context, bucket, objectPath string) (signedURL, publicURL string, err error) {
 	urlPath := fmt.Sprintf("/object/upload/sign/%s/%s", bucket, objectPath)
 
-	resp, err := c.DoRequest("POST", urlPath, struct{}{})
+	// issuing an upload token has no side effects, so it is safe to retry
+	data, err := c.doRaw(ctx, request{
+		method:      "POST",
+		path:        urlPath,
+		body:        []byte("{}"),
+		contentType: "application/json",
+		idempotent:  true,
+	})
 	if err != nil {
 		return "", "", fmt.Errorf("failed request: %w", err)
 	}
-	defer resp.Body.Close()
-
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to read body: %w", err)
-	}
-
-	if resp.StatusCode >= 300 {
-		return "", "", fmt.Errorf("supabase error: %s", string(data))
-	}
 
 	var res SignUploadFile
 	if err := json.Unmarshal(data, &res); err != nil {
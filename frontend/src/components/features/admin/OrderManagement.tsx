@@ -1,6 +1,7 @@
 "use client"
 
 import { useOrders } from "@/context/OrderContext"
+import { getOrderSlip } from "@/services/order_service"
 import { useState } from "react"
 
 const OrderManagement = () => {
@@ -10,6 +11,22 @@ const OrderManagement = () => {
   const safeOrders = orders ?? []
 
   const [selectedSlip, setSelectedSlip] = useState<string | null>(null)
+  // orders whose slip was looked up and not found
+  const [missingSlips, setMissingSlips] = useState<Record<string, boolean>>({})
+
+  // slip links expire after 10 minutes, so sign one each time it is opened
+  const openSlip = async (orderId: string) => {
+    try {
+      const url = await getOrderSlip(orderId)
+      if (url) {
+        setSelectedSlip(url)
+      } else {
+        setMissingSlips((prev) => ({ ...prev, [orderId]: true }))
+      }
+    } catch {
+      alert("ไม่สามารถโหลดสลิปได้")
+    }
+  }
   type OrderStatus = "pending" | "confirmed" | "shipping" | "delivered"
 
   const getStatusLabel = (status: string): string => {
@@ -169,34 +186,12 @@ const OrderManagement = () => {
                   <p className="text-lg font-bold text-amber-600 mb-2">
                     ฿{order.total.toLocaleString()}
                   </p>
-                  {order.public_url ? (
-                    <div className="text-sm space-y-2">
-                      <div className="flex items-center gap-1 text-green-600">
-                        <span>✓</span>
-                        <span>มีสลิปการโอน</span>
-                      </div>
-                      <div className="border rounded p-2 bg-gray-50">
-                        <p className="font-medium text-xs mb-1">
-                          📄 สลิปการโอน
-                        </p>
-                        <img
-                          src={order.public_url}
-                          alt="Payment slip"
-                          className="max-w-full h-24 object-contain border rounded cursor-pointer hover:opacity-80 transition-opacity"
-                          onClick={() =>
-                            setSelectedSlip(order.public_url as string)
-                          }
-                          onError={(e) => {
-                            const target = e.target as HTMLImageElement
-                            target.style.display = "none"
-                            const fallback = document.createElement("div")
-                            fallback.className = "text-xs text-gray-500 p-2"
-                            fallback.textContent = "ไม่สามารถโหลดสลิปได้"
-                            target.parentNode?.appendChild(fallback)
-                          }}
-                        />
-                      </div>
-                    </div>
+                  {!missingSlips[order.order_id] ? (
+                    <button
+                      onClick={() => openSlip(order.order_id)}
+                      className="text-sm border rounded px-3 py-1 bg-gray-50 hover:bg-gray-100">
+                      📄 ดูสลิปการโอน
+                    </button>
                   ) : (
                     <div className="text-sm text-red-500">
                       <span>✗ ไม่มีสลิปการโอน</span>
@@ -1,4 +1,4 @@
-import axios from "axios"
+import axios, { AxiosError, InternalAxiosRequestConfig } from "axios"
 
 const BASE_URL = "https://easybakery.onrender.com/api"
 
@@ -6,3 +6,39 @@ export const api = axios.create({
   baseURL: BASE_URL,
   withCredentials: true,
 })
+
+// Access tokens are short lived. On a 401 refresh once using the refresh
+// cookie and replay the request; concurrent failures share one refresh.
+let refreshing: Promise<void> | null = null
+
+api.interceptors.response.use(undefined, async (error: AxiosError) => {
+  const config = error.config as
+    | (InternalAxiosRequestConfig & { _retried?: boolean })
+    | undefined
+  const url = config?.url ?? ""
+  if (
+    error.response?.status !== 401 ||
+    !config ||
+    config._retried ||
+    url.includes("/user/login") ||
+    url.includes("/user/refresh")
+  ) {
+    return Promise.reject(error)
+  }
+
+  config._retried = true
+  if (!refreshing) {
+    refreshing = api
+      .post("/user/refresh")
+      .then(() => undefined)
+      .finally(() => {
+        refreshing = null
+      })
+  }
+  try {
+    await refreshing
+  } catch {
+    return Promise.reject(error)
+  }
+  return api(config)
+})
@@ -23,9 +23,12 @@ export const deleteCart = async (): Promise<string> => {
   }
 }
 
-export const checkout = async (): Promise<any> => {
+export const checkout = async (addressId?: number): Promise<any> => {
   try {
-    const response = await api.post(`${BASE_CART}/checkout`)
+    const response = await api.post(
+      `${BASE_CART}/checkout`,
+      addressId !== undefined ? { address_id: addressId } : undefined
+    )
     return response.data
   } catch (error) {
     console.error("Checkout cart error:", error)
@@ -1,3 +1,4 @@
+import { isAxiosError } from "axios"
 import { api } from "./api"
 import { Order, OrderStatus } from "@/types/order_type"
 import { uploadImage } from "./product_service"
@@ -44,6 +45,20 @@ export const deleteOrderById = async (orderId: string): Promise<Order> => {
   }
 }
 
+// signed link to an order's slip, valid for 10 minutes; null when none was uploaded
+export const getOrderSlip = async (orderId: string): Promise<string | null> => {
+  try {
+    const response = await api.get(`/admin/orders/${orderId}/slip`)
+    return response.data.slip_url
+  } catch (error) {
+    if (isAxiosError(error) && error.response?.status === 404) {
+      return null
+    }
+    console.error("Get order slip error:", error)
+    throw error
+  }
+}
+
 // upload slip
 export const uploadOrderSlip = async (orderId: string, file: File): Promise<Order> => {
   try {
@@ -21,7 +21,7 @@ export interface Order {
   order_id: string
   total: number
   status: OrderStatus
-  public_url?: string
+  slip_url?: string
   upload_url?: string
   create_at: Date
 